@@ -2,7 +2,7 @@ package openpayments
 
 import (
 	"bytes"
-	"crypto/ed25519"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -51,9 +51,14 @@ type AuthenticatedClient struct {
 	httpClient       *http.Client
 	preSignHook      func(req *http.Request)
 	postSignHook     func(req *http.Request)
+	signatureHook    func(req *http.Request, signatureBase string)
 	walletAddressUrl string /** The wallet address which the client will identify itself by */
-	privateKey       ed25519.PrivateKey
-	keyId            string
+	keys             httpsignatureutils.KeyProvider
+	grantStore       GrantStore
+	recovery         *tokenRecoverer
+	signers          *tokenSigners
+	responses        *responseVerifier
+	checkSigningKey  bool // Set by WithSigningKeyCheck.
 	WalletAddress    *WalletAddressService
 	Grant            *GrantService
 	IncomingPayment  *IncomingPaymentService
@@ -87,16 +92,113 @@ func WithPostSignHook(hook func(req *http.Request)) AuthenticatedClientOption {
 	}
 }
 
-func NewAuthenticatedClient(walletAddressUrl string, privateKey string, keyId string, opts ...AuthenticatedClientOption) (*AuthenticatedClient, error) {
-	if len(walletAddressUrl) > 0 && walletAddressUrl[0] == '$' {
-		return nil, fmt.Errorf("invalid wallet address: %q (cannot start with '$')", walletAddressUrl)
+// WithSignatureBaseHook calls hook with the signature base of every signed
+// request, e.g. to log it when a server rejects the signature. Compare it with
+// the server's base using httpsignatureutils.DiagnoseSignature.
+func WithSignatureBaseHook(hook func(req *http.Request, signatureBase string)) AuthenticatedClientOption {
+	return func(c *AuthenticatedClient) {
+		c.signatureHook = hook
 	}
+}
+
+// WithGrantStore persists grants and access tokens returned by the grant and
+// token services in store.
+func WithGrantStore(store GrantStore) AuthenticatedClientOption {
+	return func(c *AuthenticatedClient) {
+		c.grantStore = store
+	}
+}
 
-	edKey, err := httpsignatureutils.LoadKey(privateKey)
+// ResponseSignerResolver returns the wallet address whose JWKS holds the keys
+// the server that sent resp signs its responses with, or "" if the server
+// doesn't sign them.
+type ResponseSignerResolver func(resp *http.Response) (walletAddress string, err error)
+
+type responseVerifier struct {
+	resolve  ResponseSignerResolver
+	opts     []SignatureVerifierOption
+	verifier *SignatureVerifier
+}
+
+func (rv *responseVerifier) verify(resp *http.Response) error {
+	// Error responses are returned as they are, so the caller sees the
+	// server's error rather than a signature failure
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil
+	}
+	walletAddress, err := rv.resolve(resp)
+	if err != nil {
+		return err
+	}
+	if walletAddress == "" {
+		return nil
+	}
+	return rv.verifier.VerifyResponse(resp, walletAddress)
+}
+
+// WithResponseVerification verifies the signatures of responses to signed
+// requests against the keys their server publishes. resolve picks the wallet
+// address serving the JWKS; if nil, responses carrying a Signature header are
+// verified against the JWKS at the origin of the request URL and unsigned ones
+// are accepted, so pass a resolver to require signatures from a server. Error
+// responses aren't verified. opts configure key caching and validation.
+func WithResponseVerification(resolve ResponseSignerResolver, opts ...SignatureVerifierOption) AuthenticatedClientOption {
+	if resolve == nil {
+		resolve = func(resp *http.Response) (string, error) {
+			if resp.Header.Get("Signature") == "" {
+				return "", nil
+			}
+			return resp.Request.URL.Scheme + "://" + resp.Request.URL.Host, nil
+		}
+	}
+	return func(c *AuthenticatedClient) {
+		c.responses = &responseVerifier{resolve: resolve, opts: opts}
+	}
+}
+
+func NewAuthenticatedClient(walletAddressUrl string, privateKey string, keyId string, opts ...AuthenticatedClientOption) (*AuthenticatedClient, error) {
+	key, err := httpsignatureutils.LoadPrivateKey(privateKey)
 	if err != nil {
 		return nil, fmt.Errorf("error loading private key: %w", err)
 	}
 
+	return NewAuthenticatedClientWithSigner(walletAddressUrl, httpsignatureutils.NewSigner(key, keyId), opts...)
+}
+
+// NewAuthenticatedClientWithSigner creates a client that signs requests with
+// signer, e.g. one backed by an HSM or KMS, instead of an in-memory key.
+func NewAuthenticatedClientWithSigner(walletAddressUrl string, signer httpsignatureutils.Signer, opts ...AuthenticatedClientOption) (*AuthenticatedClient, error) {
+	if signer == nil {
+		return nil, fmt.Errorf("missing signer")
+	}
+
+	return NewAuthenticatedClientWithKeyRing(walletAddressUrl, httpsignatureutils.NewKeyRing(signer), opts...)
+}
+
+// NewAuthenticatedClientWithKeyRing creates a client that signs each request
+// with the key ring's active key at the time of the request. Keys can be
+// rotated through the ring without rebuilding the client.
+func NewAuthenticatedClientWithKeyRing(walletAddressUrl string, keyRing *httpsignatureutils.KeyRing, opts ...AuthenticatedClientOption) (*AuthenticatedClient, error) {
+	if keyRing == nil {
+		return nil, fmt.Errorf("missing key ring")
+	}
+	return NewAuthenticatedClientWithKeyProvider(walletAddressUrl, keyRing, opts...)
+}
+
+// NewAuthenticatedClientWithKeyProvider creates a client that asks keys for
+// the signing key of every request, e.g. a httpsignatureutils.FileKeyProvider
+// that reloads rotated key files.
+func NewAuthenticatedClientWithKeyProvider(walletAddressUrl string, keys httpsignatureutils.KeyProvider, opts ...AuthenticatedClientOption) (*AuthenticatedClient, error) {
+	if len(walletAddressUrl) > 0 && walletAddressUrl[0] == '$' {
+		return nil, fmt.Errorf("invalid wallet address: %q (cannot start with '$')", walletAddressUrl)
+	}
+	if keys == nil {
+		return nil, fmt.Errorf("missing key provider")
+	}
+	if _, err := keys.Active(); err != nil {
+		return nil, err
+	}
+
 	httpClient := &http.Client{
 		Transport: http.DefaultTransport,
 	}
@@ -104,36 +206,80 @@ func NewAuthenticatedClient(walletAddressUrl string, privateKey string, keyId st
 	c := &AuthenticatedClient{
 		httpClient:       httpClient,
 		walletAddressUrl: walletAddressUrl,
-		privateKey:       edKey,
-		keyId:            keyId,
+		keys:             keys,
+		signers:          newTokenSigners(),
 	}
 
 	for _, opt := range opts {
 		opt(c)
 	}
 
+	// resource server requests may be retried after recovering the token
+	doResource := c.DoSigned
+	if c.recovery != nil {
+		c.recovery.client = c
+		doResource = c.recovery.DoSigned
+	}
+
 	c.WalletAddress = &WalletAddressService{DoUnsigned: c.httpClient.Do}
+	if c.responses != nil {
+		c.responses.verifier = NewSignatureVerifier(c.WalletAddress, c.responses.opts...)
+	}
 	c.IncomingPayment = &IncomingPaymentService{
 		DoUnsigned: httpClient.Do,
-		DoSigned:   c.DoSigned,
+		DoSigned:   doResource,
 	}
 	c.Grant = &GrantService{
 		DoSigned: c.DoSigned,
 		client:   c.walletAddressUrl,
+		store:    c.grantStore,
+		recovery: c.recovery,
+		signers:  c.signers,
 	}
 	c.Quote = &QuoteService{
-		DoSigned: c.DoSigned,
+		DoSigned: doResource,
 	}
 	c.Token = &TokenService{
 		DoSigned: c.DoSigned,
+		store:    c.grantStore,
+		recovery: c.recovery,
+		signers:  c.signers,
 	}
 	c.OutgoingPayment = &OutgoingPaymentService{
-		DoSigned: c.DoSigned,
+		DoSigned: doResource,
+	}
+
+	if c.checkSigningKey {
+		ctx, cancel := context.WithTimeout(context.Background(), signingKeyCheckTimeout)
+		defer cancel()
+		if err := c.VerifySigningKey(ctx); err != nil {
+			return nil, err
+		}
 	}
 
 	return c, nil
 }
 
+// requestSigner picks the signer for req: an explicit signer in its context,
+// then the ephemeral key of the directed identity grant its token belongs to,
+// then the active key of the key provider.
+func (c *AuthenticatedClient) requestSigner(req *http.Request) (httpsignatureutils.Signer, error) {
+	if signer := signerFromContext(req.Context()); signer != nil {
+		return signer, nil
+	}
+	if signer := c.signers.forRequest(req.Header.Get("Authorization")); signer != nil {
+		return signer, nil
+	}
+	return c.keys.Active()
+}
+
+// KeyRing returns the key ring the client signs requests with, or nil if the
+// client was created with another key provider.
+func (c *AuthenticatedClient) KeyRing() *httpsignatureutils.KeyRing {
+	keyRing, _ := c.keys.(*httpsignatureutils.KeyRing)
+	return keyRing
+}
+
 func (c *AuthenticatedClient) DoSigned(req *http.Request) (*http.Response, error) {
 	if c.preSignHook != nil {
 		c.preSignHook(req)
@@ -157,21 +303,38 @@ func (c *AuthenticatedClient) DoSigned(req *http.Request) (*http.Response, error
 		}
 	}
 
+	signer, err := c.requestSigner(req)
+	if err != nil {
+		return nil, err
+	}
+
 	sigHeaders, err := httpsignatureutils.CreateSignatureHeaders(httpsignatureutils.SignOptions{
-		Request:    req,
-		PrivateKey: c.privateKey,
-		KeyID:      c.keyId,
+		Request: req,
+		Signer:  signer,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Signature", fmt.Sprintf("sig1=:%s:", sigHeaders.Signature))
+	req.Header.Set("Signature", sigHeaders.SignatureHeader)
 	req.Header.Set("Signature-Input", sigHeaders.SignatureInput)
 
+	if c.signatureHook != nil {
+		c.signatureHook(req, sigHeaders.SignatureBase)
+	}
+
 	if c.postSignHook != nil {
 		c.postSignHook(req)
 	}
 
-	return c.httpClient.Do(req) // #nosec G704 -- client SDK: request URLs are supplied by the library consumer
+	resp, err := c.httpClient.Do(req) // #nosec G704 -- client SDK: request URLs are supplied by the library consumer
+	if err != nil || c.responses == nil {
+		return resp, err
+	}
+
+	if err := c.responses.verify(resp); err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to verify response signature: %w", err)
+	}
+	return resp, nil
 }
@@ -6,9 +6,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	was "github.com/interledger/open-payments-go/generated/walletaddressserver"
+	"github.com/interledger/open-payments-go/httpsignatureutils"
+	"github.com/interledger/open-payments-go/httpsignatureutils/signertest"
 )
 
 func TestWalletAddress_Get_SuccessfulResponse(t *testing.T) {
@@ -66,3 +70,136 @@ func TestWalletAddress_Get_FailedResponse(t *testing.T) {
 	}
 
 }
+
+func TestNewAuthenticatedClientWithSigner(t *testing.T) {
+	signer := signertest.New("hsm-key")
+
+	var signatureInput string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signatureInput = r.Header.Get("Signature-Input")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := NewAuthenticatedClientWithSigner("https://example.com/alice", signer, WithHTTPClientAuthed(server.Client()))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	err = client.Token.Revoke(context.Background(), TokenRevokeParams{URL: server.URL + "/token/1", AccessToken: "token"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(signer.Bases()) != 1 {
+		t.Fatalf("expected 1 signature base, got %d", len(signer.Bases()))
+	}
+	if !strings.Contains(signer.LastBase(), "\"authorization\": GNAP token") {
+		t.Errorf("unexpected signature base: %s", signer.LastBase())
+	}
+	if !strings.Contains(signatureInput, `keyid="hsm-key"`) {
+		t.Errorf("unexpected Signature-Input: %s", signatureInput)
+	}
+}
+
+func TestNewAuthenticatedClientWithKeyRing_RotatesActiveKey(t *testing.T) {
+	var signatureInputs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signatureInputs = append(signatureInputs, r.Header.Get("Signature-Input"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	keyRing := httpsignatureutils.NewKeyRing(signertest.New("key-1"))
+	client, err := NewAuthenticatedClientWithKeyRing("https://example.com/alice", keyRing, WithHTTPClientAuthed(server.Client()))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	revoke := func() {
+		err := client.Token.Revoke(context.Background(), TokenRevokeParams{URL: server.URL + "/token/1", AccessToken: "token"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	revoke()
+	client.KeyRing().Rotate(signertest.New("key-2"), time.Minute)
+	revoke()
+
+	if len(signatureInputs) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(signatureInputs))
+	}
+	if !strings.Contains(signatureInputs[0], `keyid="key-1"`) || !strings.Contains(signatureInputs[1], `keyid="key-2"`) {
+		t.Errorf("unexpected Signature-Input headers: %v", signatureInputs)
+	}
+}
+
+type switchingKeyProvider struct {
+	signer httpsignatureutils.Signer
+}
+
+func (p *switchingKeyProvider) Active() (httpsignatureutils.Signer, error) {
+	return p.signer, nil
+}
+
+func TestNewAuthenticatedClientWithKeyProvider(t *testing.T) {
+	var signatureInputs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signatureInputs = append(signatureInputs, r.Header.Get("Signature-Input"))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	keys := &switchingKeyProvider{signer: signertest.New("key-1")}
+	client, err := NewAuthenticatedClientWithKeyProvider("https://example.com/alice", keys, WithHTTPClientAuthed(server.Client()))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if client.KeyRing() != nil {
+		t.Errorf("expected no key ring")
+	}
+
+	for _, keyID := range []string{"key-1", "key-2"} {
+		keys.signer = signertest.New(keyID)
+		err := client.Token.Revoke(context.Background(), TokenRevokeParams{URL: server.URL + "/token/1", AccessToken: "token"})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if len(signatureInputs) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(signatureInputs))
+	}
+	if !strings.Contains(signatureInputs[0], `keyid="key-1"`) || !strings.Contains(signatureInputs[1], `keyid="key-2"`) {
+		t.Errorf("unexpected Signature-Input headers: %v", signatureInputs)
+	}
+}
+
+func TestWithSignatureBaseHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	signer := signertest.New("key-1")
+	var bases []string
+	client, err := NewAuthenticatedClientWithSigner("https://example.com/alice", signer,
+		WithHTTPClientAuthed(server.Client()),
+		WithSignatureBaseHook(func(req *http.Request, signatureBase string) {
+			bases = append(bases, signatureBase)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	err = client.Token.Revoke(context.Background(), TokenRevokeParams{URL: server.URL + "/token/1", AccessToken: "token"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(bases) != 1 || bases[0] != signer.LastBase() {
+		t.Errorf("expected the signed base %q, got %q", signer.LastBase(), bases)
+	}
+}
@@ -0,0 +1,167 @@
+package openpayments
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	as "github.com/interledger/open-payments-go/generated/authserver"
+	"github.com/interledger/open-payments-go/httpsignatureutils"
+)
+
+type signerContextKey struct{}
+
+// contextWithSigner makes DoSigned sign requests made with ctx with signer
+// instead of the client's key.
+func contextWithSigner(ctx context.Context, signer httpsignatureutils.Signer) context.Context {
+	return context.WithValue(ctx, signerContextKey{}, signer)
+}
+
+func signerFromContext(ctx context.Context) httpsignatureutils.Signer {
+	signer, _ := ctx.Value(signerContextKey{}).(httpsignatureutils.Signer)
+	return signer
+}
+
+// newDirectedIdentity generates an ephemeral Ed25519 key and the client JWK
+// presenting it.
+func newDirectedIdentity() (httpsignatureutils.Signer, *as.ClientDirectedIdentity, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate directed identity key: %w", err)
+	}
+
+	// RFC 7638 thumbprint, so the key ID reveals nothing beyond the key itself
+	kid := httpsignatureutils.Thumbprint(pub)
+	identity := &as.ClientDirectedIdentity{
+		Jwk: httpsignatureutils.PublicKeyToAuthServerJWK(pub, kid),
+	}
+	return httpsignatureutils.NewEd25519Signer(priv, kid), identity, nil
+}
+
+// tokenSignerTTL is how long a token without an expiry stays bound to its
+// signer, and how long past its expiry an access token stays bound, so it can
+// still be rotated.
+const tokenSignerTTL = 24 * time.Hour
+
+type boundSigner struct {
+	signer  httpsignatureutils.Signer
+	expires time.Time
+}
+
+// tokenSigners binds the continuation and access tokens of directed identity
+// grants to their ephemeral signer, so requests made with those tokens are
+// signed with the key presented in the grant request. Bindings expire, so
+// tokens the caller drops are forgotten. A nil *tokenSigners binds nothing.
+type tokenSigners struct {
+	mu        sync.Mutex
+	signers   map[string]boundSigner
+	now       func() time.Time
+	lastSweep time.Time
+}
+
+func newTokenSigners() *tokenSigners {
+	return &tokenSigners{signers: make(map[string]boundSigner), now: time.Now}
+}
+
+// bind binds token to signer until tokenSignerTTL after expiresIn seconds, if
+// set. ts.mu must be held.
+func (ts *tokenSigners) bind(token string, signer httpsignatureutils.Signer, expiresIn *int) {
+	now := ts.now()
+	if now.Sub(ts.lastSweep) >= time.Minute {
+		ts.lastSweep = now
+		for t, bound := range ts.signers {
+			if now.After(bound.expires) {
+				delete(ts.signers, t)
+			}
+		}
+	}
+	expires := now.Add(tokenSignerTTL)
+	if expiresIn != nil {
+		expires = expires.Add(time.Duration(*expiresIn) * time.Second)
+	}
+	ts.signers[token] = boundSigner{signer: signer, expires: expires}
+}
+
+func (ts *tokenSigners) bindGrant(signer httpsignatureutils.Signer, grant Grant) {
+	if ts == nil || signer == nil {
+		return
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if grant.Continue.AccessToken.Value != "" {
+		ts.bind(grant.Continue.AccessToken.Value, signer, nil)
+	}
+	if grant.AccessToken != nil {
+		ts.bind(grant.AccessToken.Value, signer, grant.AccessToken.ExpiresIn)
+	}
+}
+
+// continued moves the binding of the continuation token previousToken to the
+// tokens of grant, the response to continuing with it, and reports whether it
+// was bound. A continue response replaces the continuation token, so the old
+// one is no longer used.
+func (ts *tokenSigners) continued(previousToken string, grant Grant) bool {
+	if ts == nil {
+		return false
+	}
+	ts.mu.Lock()
+	bound, ok := ts.signers[previousToken]
+	delete(ts.signers, previousToken)
+	ts.mu.Unlock()
+	if ok {
+		ts.bindGrant(bound.signer, grant)
+	}
+	return ok
+}
+
+// rebind binds token to the signer of previousToken, if any.
+func (ts *tokenSigners) rebind(previousToken string, token as.AccessToken) {
+	if ts == nil {
+		return
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if bound, ok := ts.signers[previousToken]; ok {
+		delete(ts.signers, previousToken)
+		ts.bind(token.Value, bound.signer, token.ExpiresIn)
+	}
+}
+
+func (ts *tokenSigners) unbind(token string) {
+	if ts == nil {
+		return
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	delete(ts.signers, token)
+}
+
+func (ts *tokenSigners) lookup(token string) httpsignatureutils.Signer {
+	if ts == nil || token == "" {
+		return nil
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	bound, ok := ts.signers[token]
+	if !ok {
+		return nil
+	}
+	if ts.now().After(bound.expires) {
+		delete(ts.signers, token)
+		return nil
+	}
+	return bound.signer
+}
+
+// forRequest returns the signer bound to the GNAP token in authorization.
+func (ts *tokenSigners) forRequest(authorization string) httpsignatureutils.Signer {
+	token, ok := strings.CutPrefix(authorization, "GNAP ")
+	if !ok {
+		return nil
+	}
+	return ts.lookup(token)
+}
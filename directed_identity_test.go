@@ -0,0 +1,168 @@
+package openpayments_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	openpayments "github.com/interledger/open-payments-go"
+	as "github.com/interledger/open-payments-go/generated/authserver"
+	"github.com/interledger/open-payments-go/httpsignatureutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingTransport keeps the signed requests as sent, since the server side
+// doesn't see the full target URI.
+type recordingTransport struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rt *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body = io.NopCloser(strings.NewReader(string(body)))
+	}
+	sent := req.Clone(context.Background())
+	sent.Body = io.NopCloser(strings.NewReader(string(body)))
+	rt.mu.Lock()
+	rt.requests = append(rt.requests, sent)
+	rt.bodies = append(rt.bodies, body)
+	rt.mu.Unlock()
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func verifySentSignature(t *testing.T, req *http.Request, pub ed25519.PublicKey) error {
+	t.Helper()
+	return httpsignatureutils.ValidateSignature(httpsignatureutils.NewValidationOptions(req, req.Header, pub))
+}
+
+func TestGrantRequest_DirectedIdentity(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/":
+			_ = json.NewEncoder(w).Encode(openpayments.Grant{
+				AccessToken: &as.AccessToken{Value: "access-token", Manage: server.URL + "/token/1"},
+				Continue: as.Continue{
+					Uri: server.URL + "/continue/1",
+					AccessToken: struct {
+						Value string `json:"value"`
+					}{Value: "continue-token"},
+				},
+			})
+		case r.URL.Path == "/token/1":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"access_token": as.AccessToken{Value: "rotated-token", Manage: server.URL + "/token/2"},
+			})
+		default:
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	transport := &recordingTransport{}
+	client, err := openpayments.NewAuthenticatedClient(walletAddress, pk, keyID, openpayments.WithHTTPClientAuthed(&http.Client{Transport: transport}))
+	assert.NoError(t, err)
+
+	body, err := openpayments.NewIncomingPaymentGrantRequest(openpayments.IncomingPaymentGrantParams{
+		Actions: []as.AccessIncomingActions{as.AccessIncomingActionsCreate},
+	})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	grant, err := client.Grant.Request(ctx, openpayments.GrantRequestParams{
+		URL:              server.URL + "/",
+		RequestBody:      body,
+		DirectedIdentity: true,
+	})
+	assert.NoError(t, err)
+
+	_, err = client.IncomingPayment.Create(ctx, openpayments.IncomingPaymentCreateParams{BaseURL: server.URL, AccessToken: grant.AccessToken.Value})
+	assert.NoError(t, err)
+	rotated, err := client.Token.Rotate(ctx, openpayments.TokenRotateParams{URL: grant.AccessToken.Manage, AccessToken: grant.AccessToken.Value})
+	assert.NoError(t, err)
+	_, err = client.IncomingPayment.Create(ctx, openpayments.IncomingPaymentCreateParams{BaseURL: server.URL, AccessToken: rotated.Value})
+	assert.NoError(t, err)
+	_, err = client.IncomingPayment.Create(ctx, openpayments.IncomingPaymentCreateParams{BaseURL: server.URL, AccessToken: accessToken})
+	assert.NoError(t, err)
+
+	var sent struct {
+		Client as.ClientDirectedIdentity `json:"client"`
+	}
+	assert.NoError(t, json.Unmarshal(transport.bodies[0], &sent))
+	assert.Equal(t, "OKP", string(sent.Client.Jwk.Kty))
+	x, err := base64.RawURLEncoding.DecodeString(sent.Client.Jwk.X)
+	assert.NoError(t, err)
+	ephemeral := ed25519.PublicKey(x)
+
+	// grant request, create, rotate and create with the rotated token use the ephemeral key
+	for _, req := range transport.requests[:4] {
+		assert.Contains(t, req.Header.Get("Signature-Input"), `keyid="`+sent.Client.Jwk.Kid+`"`)
+		assert.NoError(t, verifySentSignature(t, req, ephemeral), req.URL.String())
+	}
+	// unrelated tokens still use the wallet address key
+	assert.Contains(t, transport.requests[4].Header.Get("Signature-Input"), `keyid="`+keyID+`"`)
+	assert.Error(t, verifySentSignature(t, transport.requests[4], ephemeral))
+}
+
+func TestGrantRequest_DirectedIdentityWithClientOverride(t *testing.T) {
+	client, err := openpayments.NewAuthenticatedClient(walletAddress, pk, keyID)
+	assert.NoError(t, err)
+
+	body, err := openpayments.NewIncomingPaymentGrantRequest(openpayments.IncomingPaymentGrantParams{
+		Actions: []as.AccessIncomingActions{as.AccessIncomingActionsCreate},
+	})
+	assert.NoError(t, err)
+
+	_, err = client.Grant.Request(context.Background(), openpayments.GrantRequestParams{
+		URL:              "https://auth.example.com/",
+		RequestBody:      body,
+		ClientOverride:   &as.ClientDirectedIdentity{},
+		DirectedIdentity: true,
+	})
+	assert.ErrorContains(t, err, "mutually exclusive")
+}
+
+func TestGrantRequest_DirectedIdentityNotStored(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(openpayments.Grant{
+			AccessToken: &as.AccessToken{Value: "access-token", Manage: server.URL + "/token/1"},
+			Continue:    as.Continue{Uri: server.URL + "/continue/1"},
+		})
+	}))
+	defer server.Close()
+
+	store := openpayments.NewMemoryGrantStore()
+	client, err := openpayments.NewAuthenticatedClient(walletAddress, pk, keyID,
+		openpayments.WithHTTPClientAuthed(server.Client()),
+		openpayments.WithGrantStore(store))
+	assert.NoError(t, err)
+
+	body, err := openpayments.NewIncomingPaymentGrantRequest(openpayments.IncomingPaymentGrantParams{
+		Actions: []as.AccessIncomingActions{as.AccessIncomingActionsCreate},
+	})
+	assert.NoError(t, err)
+	_, err = client.Grant.Request(context.Background(), openpayments.GrantRequestParams{
+		URL:              server.URL + "/",
+		RequestBody:      body,
+		DirectedIdentity: true,
+	})
+	assert.NoError(t, err)
+
+	// the ephemeral key isn't persisted, so the stored token would be unusable
+	grants, err := store.List(context.Background())
+	assert.NoError(t, err)
+	assert.Empty(t, grants)
+}
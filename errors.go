@@ -2,11 +2,55 @@ package openpayments
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+
+	as "github.com/interledger/open-payments-go/generated/authserver"
+)
+
+// Errors for the codes of Open Payments error responses. errors.Is matches an
+// *OpenPaymentsClientError against the error for its code.
+var (
+	// Auth server codes
+	ErrInvalidClient       = errors.New("open payments: invalid_client")
+	ErrInvalidContinuation = errors.New("open payments: invalid_continuation")
+	ErrInvalidRequest      = errors.New("open payments: invalid_request")
+	ErrInvalidRotation     = errors.New("open payments: invalid_rotation")
+	ErrRequestDenied       = errors.New("open payments: request_denied")
+	ErrTooFast             = errors.New("open payments: too_fast")
+
+	// Resource server codes
+	ErrInvalidToken = errors.New("open payments: invalid_token")
+)
+
+// Errors for the statuses of Open Payments error responses, for servers that
+// respond without a code. errors.Is matches an *OpenPaymentsClientError
+// against the error for its status.
+var (
+	ErrUnauthorized = errors.New("open payments: unauthorized")
+	ErrForbidden    = errors.New("open payments: forbidden")
+	ErrNotFound     = errors.New("open payments: not found")
 )
 
+var errorCodes = map[string]error{
+	string(as.InvalidClient):       ErrInvalidClient,
+	string(as.InvalidContinuation): ErrInvalidContinuation,
+	string(as.InvalidRequest):      ErrInvalidRequest,
+	string(as.InvalidRotation):     ErrInvalidRotation,
+	string(as.RequestDenied):       ErrRequestDenied,
+	string(as.TooFast):             ErrTooFast,
+	"invalid_token":                ErrInvalidToken,
+}
+
+var statusErrors = map[int]error{
+	http.StatusUnauthorized: ErrUnauthorized,
+	http.StatusForbidden:    ErrForbidden,
+	http.StatusNotFound:     ErrNotFound,
+}
+
 type OpenPaymentsClientError struct {
 	Description      string
 	Status           int
@@ -26,6 +70,54 @@ func (e *OpenPaymentsClientError) Error() string {
 		e.Method, e.URL, e.Description)
 }
 
+// Is reports whether target is the error for e's code or status.
+func (e *OpenPaymentsClientError) Is(target error) bool {
+	if target == nil {
+		return false
+	}
+	return errorCodes[e.Code] == target || statusErrors[e.Status] == target
+}
+
+// IsRetryable reports whether the request that failed with err may succeed if
+// retried unchanged after a while: too_fast, 429 and 5xx responses, and
+// network timeouts.
+func IsRetryable(err error) bool {
+	if errors.Is(err, ErrTooFast) {
+		return true
+	}
+	var clientErr *OpenPaymentsClientError
+	if errors.As(err, &clientErr) {
+		return clientErr.Status == http.StatusTooManyRequests || clientErr.Status >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// IsAuthError reports whether err is a rejection of the client, its grant or
+// its access token, which needs a new grant, token or key to resolve.
+func IsAuthError(err error) bool {
+	for _, target := range []error{
+		ErrInvalidClient, ErrInvalidContinuation, ErrInvalidRotation, ErrRequestDenied,
+		ErrInvalidToken, ErrUnauthorized, ErrForbidden,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPermanent reports whether err is an Open Payments error response that
+// retrying the request unchanged won't resolve, such as a 4xx other than
+// too_fast or 429.
+func IsPermanent(err error) bool {
+	var clientErr *OpenPaymentsClientError
+	if !errors.As(err, &clientErr) || IsRetryable(err) {
+		return false
+	}
+	return clientErr.Status < 500
+}
+
 func newClientErrorFromResponse(req *http.Request, resp *http.Response) *OpenPaymentsClientError {
 	bodyBytes, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
 
@@ -2,6 +2,7 @@ package openpayments
 
 import (
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"strings"
@@ -131,3 +132,48 @@ func TestOpenPaymentsClientError_ErrorsAs(t *testing.T) {
 		t.Errorf("expected code 'invalid_token', got %q", clientErr.Code)
 	}
 }
+
+func TestOpenPaymentsClientError_Is(t *testing.T) {
+	newErr := func(status int, body string) error {
+		req, _ := http.NewRequest(http.MethodPost, "https://auth.example.com/continue/1", nil)
+		err := newClientErrorFromResponse(req, &http.Response{
+			StatusCode: status,
+			Status:     http.StatusText(status),
+			Body:       io.NopCloser(strings.NewReader(body)),
+		})
+		return fmt.Errorf("failed to continue grant: %w", err)
+	}
+
+	denied := newErr(401, `{"error":{"description":"denied","code":"request_denied"}}`)
+	if !errors.Is(denied, ErrRequestDenied) {
+		t.Errorf("expected errors.Is to match ErrRequestDenied")
+	}
+	if !errors.Is(denied, ErrUnauthorized) {
+		t.Errorf("expected errors.Is to match ErrUnauthorized")
+	}
+	if errors.Is(denied, ErrTooFast) || errors.Is(denied, ErrNotFound) {
+		t.Errorf("expected errors.Is not to match other codes and statuses")
+	}
+	if !IsAuthError(denied) || !IsPermanent(denied) || IsRetryable(denied) {
+		t.Errorf("expected request_denied to be a permanent auth error")
+	}
+
+	tooFast := newErr(400, `{"error":{"description":"slow down","code":"too_fast"}}`)
+	if !errors.Is(tooFast, ErrTooFast) || !IsRetryable(tooFast) || IsPermanent(tooFast) || IsAuthError(tooFast) {
+		t.Errorf("expected too_fast to be retryable")
+	}
+
+	unavailable := newErr(503, "")
+	if !IsRetryable(unavailable) || IsPermanent(unavailable) {
+		t.Errorf("expected 503 to be retryable")
+	}
+
+	notFound := newErr(404, "")
+	if !errors.Is(notFound, ErrNotFound) || !IsPermanent(notFound) || IsAuthError(notFound) {
+		t.Errorf("expected 404 to be permanent")
+	}
+
+	if IsRetryable(errors.New("boom")) || IsPermanent(errors.New("boom")) || IsAuthError(errors.New("boom")) {
+		t.Errorf("expected other errors not to be classified")
+	}
+}
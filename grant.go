@@ -4,25 +4,22 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	as "github.com/interledger/open-payments-go/generated/authserver"
+	"github.com/interledger/open-payments-go/httpsignatureutils"
 )
 
-// TODO: Improve cumbersome work of forming grant requests. See tests.
-// - make new NewIncomingPaymentGrantRequest, etc. methods which
-//   just have parmams for access items (and what else? just interact i guess?).
-//   This means the methods need to be quite a bit smarter about grant requests but
-//   that seems like the core purpose of the sdk - abstract away the complexity of sending
-//   the request.
-// - otherwise make helpers? ie for making Incoming Payment access token.
-//   NewIncomingPaymentAccessToken that takes access items (anything else? maybe not)
-
 type GrantService struct {
 	DoSigned RequestDoer
 	client   string
+	store    GrantStore
+	recovery *tokenRecoverer
+	signers  *tokenSigners
 }
 
 type GrantRequestParams struct {
@@ -32,6 +29,13 @@ type GrantRequestParams struct {
 	// identification with a JWK (directed identity). Per spec, only valid for
 	// non-interactive grants (e.g. incoming payments).
 	ClientOverride *as.ClientDirectedIdentity
+	// DirectedIdentity generates an ephemeral Ed25519 key for this grant and
+	// presents its JWK as the client instead of the wallet address. The grant
+	// request, and later requests made by this client with the grant's
+	// continuation or access tokens, are signed with the ephemeral key.
+	// The key only lives in memory, so these grants aren't saved to the grant
+	// store. Can't be combined with ClientOverride.
+	DirectedIdentity bool
 }
 
 type GrantCancelParams struct {
@@ -45,6 +49,16 @@ type GrantContinueParams struct {
 	InteractRef string
 }
 
+type GrantContinueUntilDoneParams struct {
+	Continue    as.Continue // The continuation details of the pending grant.
+	InteractRef string      // Sent until the auth server accepts it, as it is single-use.
+	// DefaultWait is used when the auth server doesn't specify a wait.
+	// Defaults to 5 seconds as recommended by GNAP.
+	DefaultWait time.Duration
+	// TooFastBackoff is added to the wait after a too_fast error. Defaults to 5 seconds.
+	TooFastBackoff time.Duration
+}
+
 // TODO: Address missing grant request type in generated types.
 // This re-constructs from the generated types therefore is prone
 // to drift from OpenAPI spec.
@@ -53,6 +67,39 @@ type Grant struct {
 	AccessToken *as.AccessToken      `json:"access_token,omitempty"`
 	Subject     *as.Subject          `json:"subject,omitempty"`
 	Continue    as.Continue          `json:"continue"`
+
+	// InteractNonce is the client nonce sent in interact.finish, if any, and
+	// GrantEndpoint is the URL the grant was requested from. Both are recorded
+	// by GrantService.Request for VerifyInteractFinish.
+	InteractNonce string `json:"interact_nonce,omitempty"`
+	GrantEndpoint string `json:"grant_endpoint,omitempty"`
+}
+
+// GrantSerializationVersion is the version written by Grant.MarshalJSON.
+// Grants without a version, such as auth server responses, are accepted.
+const GrantSerializationVersion = 1
+
+type grantFields Grant
+
+type versionedGrant struct {
+	Version int `json:"version"`
+	grantFields
+}
+
+func (gr Grant) MarshalJSON() ([]byte, error) {
+	return json.Marshal(versionedGrant{Version: GrantSerializationVersion, grantFields: grantFields(gr)})
+}
+
+func (gr *Grant) UnmarshalJSON(data []byte) error {
+	var v versionedGrant
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Version > GrantSerializationVersion {
+		return fmt.Errorf("unsupported grant serialization version %d", v.Version)
+	}
+	*gr = Grant(v.grantFields)
+	return nil
 }
 
 func (gr *Grant) IsInteractive() bool {
@@ -77,8 +124,9 @@ func (gr *Grant) IsGrantedWithSubject() bool {
 }
 
 type parsedGrantRequest struct {
-	Client *as.Client
-	encode func() (as.GrantRequest, error)
+	Client   *as.Client
+	Interact *as.InteractRequest
+	encode   func() (as.GrantRequest, error)
 }
 
 // decodes body into whichever concrete variant the caller built (access-token or subject)
@@ -96,7 +144,8 @@ func parseRequest(body as.GrantRequest) (parsedGrantRequest, error) {
 			return parsedGrantRequest{}, fmt.Errorf("invalid subject grant request body: %w", err)
 		}
 		return parsedGrantRequest{
-			Client: &subjectReq.Client,
+			Client:   &subjectReq.Client,
+			Interact: &subjectReq.Interact,
 			encode: func() (as.GrantRequest, error) {
 				var g as.GrantRequest
 				return g, g.FromGrantRequestWithSubject(subjectReq)
@@ -105,7 +154,8 @@ func parseRequest(body as.GrantRequest) (parsedGrantRequest, error) {
 	}
 
 	return parsedGrantRequest{
-		Client: &tokenReq.Client,
+		Client:   &tokenReq.Client,
+		Interact: tokenReq.Interact,
 		encode: func() (as.GrantRequest, error) {
 			var g as.GrantRequest
 			return g, g.FromGrantRequestWithAccessToken(tokenReq)
@@ -119,8 +169,21 @@ func (gs *GrantService) Request(ctx context.Context, params GrantRequestParams)
 		return Grant{}, err
 	}
 
-	if params.ClientOverride != nil {
-		err = parsed.Client.FromClientDirectedIdentity(*params.ClientOverride)
+	clientOverride := params.ClientOverride
+	var signer httpsignatureutils.Signer
+	if params.DirectedIdentity {
+		if clientOverride != nil {
+			return Grant{}, fmt.Errorf("ClientOverride and DirectedIdentity are mutually exclusive")
+		}
+		signer, clientOverride, err = newDirectedIdentity()
+		if err != nil {
+			return Grant{}, err
+		}
+		ctx = contextWithSigner(ctx, signer)
+	}
+
+	if clientOverride != nil {
+		err = parsed.Client.FromClientDirectedIdentity(*clientOverride)
 	} else {
 		err = parsed.Client.FromClientWalletAddress(as.ClientWalletAddress{WalletAddress: gs.client})
 	}
@@ -128,19 +191,31 @@ func (gs *GrantService) Request(ctx context.Context, params GrantRequestParams)
 		return Grant{}, fmt.Errorf("failed to set client: %w", err)
 	}
 
+	var interactNonce string
+	if parsed.Interact != nil && parsed.Interact.Finish != nil {
+		if parsed.Interact.Finish.Nonce == "" {
+			parsed.Interact.Finish.Nonce, err = NewInteractNonce()
+			if err != nil {
+				return Grant{}, err
+			}
+		}
+		interactNonce = parsed.Interact.Finish.Nonce
+	}
+
 	body, err := parsed.encode()
 	if err != nil {
 		return Grant{}, fmt.Errorf("failed to encode grant request body: %w", err)
 	}
 
+	if err := validateGrantRequest(body, clientOverride != nil); err != nil {
+		return Grant{}, err
+	}
+
 	reqBodyBytes, err := json.Marshal(body)
 	if err != nil {
 		return Grant{}, fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	// TODO: validation. [debit|receive]Amount limits are mutually exclusive for
-	// access token's with type outgoing-payment
-
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, params.URL, bytes.NewBuffer(reqBodyBytes))
 	if err != nil {
 		return Grant{}, fmt.Errorf("failed to create request: %w", err)
@@ -162,6 +237,21 @@ func (gs *GrantService) Request(ctx context.Context, params GrantRequestParams)
 		return Grant{}, fmt.Errorf("failed to decode response body: %w", err)
 	}
 
+	grantResponse.InteractNonce = interactNonce
+	grantResponse.GrantEndpoint = params.URL
+
+	gs.signers.bindGrant(signer, grantResponse)
+
+	if gs.recovery != nil {
+		gs.recovery.track(grantResponse, &params)
+	}
+
+	if gs.store != nil && signer == nil && grantResponse.Continue.Uri != "" {
+		if err := gs.store.Save(ctx, grantResponse); err != nil {
+			return grantResponse, fmt.Errorf("failed to save grant: %w", err)
+		}
+	}
+
 	return grantResponse, nil
 }
 
@@ -205,9 +295,101 @@ func (gs *GrantService) Continue(ctx context.Context, params GrantContinueParams
 		return Grant{}, fmt.Errorf("failed to decode continue response: %w", err)
 	}
 
+	// only ever recorded by Request
+	grantResponse.InteractNonce = ""
+	grantResponse.GrantEndpoint = ""
+
+	directed := gs.signers.continued(params.AccessToken, grantResponse)
+
+	if gs.recovery != nil {
+		gs.recovery.track(grantResponse, nil)
+	}
+
+	if gs.store != nil && !directed {
+		if err := gs.storeContinued(ctx, params.URL, grantResponse); err != nil {
+			return grantResponse, fmt.Errorf("failed to save grant: %w", err)
+		}
+	}
+
 	return grantResponse, nil
 }
 
+// storeContinued replaces the stored grant with its continue response, which
+// may carry a new continue URI.
+func (gs *GrantService) storeContinued(ctx context.Context, previousURI string, grant Grant) error {
+	if grant.Continue.Uri != previousURI {
+		if err := gs.store.Delete(ctx, previousURI); err != nil {
+			return err
+		}
+	}
+	if grant.Continue.Uri == "" {
+		return nil
+	}
+	return gs.store.Save(ctx, grant)
+}
+
+// ContinueUntilDone polls the continuation URI, honoring the wait requested by
+// the auth server, until the grant is approved, denied or ctx is done.
+// Rotated continuation tokens are used for subsequent requests and too_fast
+// errors slow down polling.
+func (gs *GrantService) ContinueUntilDone(ctx context.Context, params GrantContinueUntilDoneParams) (Grant, error) {
+	defaultWait := params.DefaultWait
+	if defaultWait == 0 {
+		defaultWait = defaultContinueWait
+	}
+	backoff := params.TooFastBackoff
+	if backoff == 0 {
+		backoff = defaultContinueWait
+	}
+
+	cont := params.Continue
+	interactRef := params.InteractRef
+	wait := continueWait(cont.Wait, defaultWait)
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return Grant{}, ctx.Err()
+		case <-timer.C:
+		}
+
+		grant, err := gs.Continue(ctx, GrantContinueParams{
+			URL:         cont.Uri,
+			AccessToken: cont.AccessToken.Value,
+			InteractRef: interactRef,
+		})
+		if err != nil {
+			if errors.Is(err, ErrTooFast) {
+				wait += backoff
+				continue
+			}
+			return Grant{}, err
+		}
+		// The interaction reference is single-use, later polls have no body
+		interactRef = ""
+
+		if grant.IsGrantedWithAccessToken() || grant.IsGrantedWithSubject() {
+			return grant, nil
+		}
+		if grant.Continue.Uri == "" {
+			return Grant{}, fmt.Errorf("continue response contained neither a grant nor continuation")
+		}
+
+		cont = grant.Continue
+		wait = continueWait(cont.Wait, defaultWait)
+	}
+}
+
+const defaultContinueWait = 5 * time.Second
+
+func continueWait(wait *int, defaultWait time.Duration) time.Duration {
+	if wait == nil {
+		return defaultWait
+	}
+	return time.Duration(*wait) * time.Second
+}
+
 func (gs *GrantService) Cancel(ctx context.Context, params GrantCancelParams) error {
 	if params.URL == "" || params.AccessToken == "" {
 		return fmt.Errorf("missing required url or access token")
@@ -229,5 +411,13 @@ func (gs *GrantService) Cancel(ctx context.Context, params GrantCancelParams) er
 		return newClientErrorFromResponse(req, resp)
 	}
 
+	gs.signers.unbind(params.AccessToken)
+
+	if gs.store != nil {
+		if err := gs.store.Delete(ctx, params.URL); err != nil {
+			return fmt.Errorf("failed to delete stored grant: %w", err)
+		}
+	}
+
 	return nil
 }
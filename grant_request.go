@@ -0,0 +1,229 @@
+package openpayments
+
+import (
+	"fmt"
+
+	as "github.com/interledger/open-payments-go/generated/authserver"
+)
+
+// IncomingPaymentGrantParams describes a grant request for incoming-payment access.
+type IncomingPaymentGrantParams struct {
+	Actions    []as.AccessIncomingActions
+	Identifier string // Optional. Restricts access to a specific wallet address or incoming payment.
+	Interact   *as.InteractRequest
+}
+
+// QuoteGrantParams describes a grant request for quote access.
+type QuoteGrantParams struct {
+	Actions  []as.AccessQuoteActions
+	Interact *as.InteractRequest
+}
+
+// OutgoingPaymentGrantParams describes a grant request for outgoing-payment access.
+// Outgoing payment grants always require interaction with the resource owner,
+// so Interact must be set.
+type OutgoingPaymentGrantParams struct {
+	Actions    []as.AccessOutgoingActions
+	Identifier string // The wallet address the outgoing payments are sent from.
+	Limits     *OutgoingPaymentLimits
+	Interact   *as.InteractRequest
+}
+
+// OutgoingPaymentLimits are the limits under which outgoing payments can be
+// created. DebitAmount and ReceiveAmount are mutually exclusive.
+type OutgoingPaymentLimits struct {
+	DebitAmount   *as.Amount
+	ReceiveAmount *as.Amount
+	Interval      string // ISO8601 repeating interval, e.g. "R/2024-01-01T00:00:00Z/P1M".
+	Receiver      string // The URL of the incoming payment that is being paid.
+}
+
+// NewRedirectInteract returns an interact request that starts a redirect
+// interaction. When finishURI is not empty the auth server redirects back to
+// it once the interaction has finished. An empty nonce is generated by
+// GrantService.Request and recorded on the returned Grant; since finishURI
+// can't carry it then, use NewInteractionRedirect with the default
+// InteractionHandler.
+func NewRedirectInteract(finishURI string, nonce string) *as.InteractRequest {
+	interact := &as.InteractRequest{
+		Start: []as.InteractRequestStart{as.InteractRequestStartRedirect},
+	}
+	if finishURI != "" {
+		interact.Finish = &struct {
+			Method as.InteractRequestFinishMethod `json:"method"`
+			Nonce  string                         `json:"nonce"`
+			Uri    string                         `json:"uri"`
+		}{
+			Method: as.InteractRequestFinishMethodRedirect,
+			Nonce:  nonce,
+			Uri:    finishURI,
+		}
+	}
+	return interact
+}
+
+func NewIncomingPaymentAccess(actions []as.AccessIncomingActions, identifier string) (as.AccessItem, error) {
+	if len(actions) == 0 {
+		return as.AccessItem{}, fmt.Errorf("incoming-payment access requires at least one action")
+	}
+	for _, action := range actions {
+		switch action {
+		case as.AccessIncomingActionsCreate, as.AccessIncomingActionsRead, as.AccessIncomingActionsReadAll,
+			as.AccessIncomingActionsList, as.AccessIncomingActionsListAll, as.AccessIncomingActionsComplete:
+		default:
+			return as.AccessItem{}, fmt.Errorf("invalid incoming-payment action: %q", action)
+		}
+	}
+
+	access := as.AccessIncoming{
+		Type:    as.IncomingPayment,
+		Actions: actions,
+	}
+	if identifier != "" {
+		access.Identifier = &identifier
+	}
+
+	var item as.AccessItem
+	if err := item.FromAccessIncoming(access); err != nil {
+		return as.AccessItem{}, fmt.Errorf("failed to encode incoming-payment access: %w", err)
+	}
+	return item, nil
+}
+
+func NewQuoteAccess(actions []as.AccessQuoteActions) (as.AccessItem, error) {
+	if len(actions) == 0 {
+		return as.AccessItem{}, fmt.Errorf("quote access requires at least one action")
+	}
+	for _, action := range actions {
+		switch action {
+		case as.Create, as.Read, as.ReadAll:
+		default:
+			return as.AccessItem{}, fmt.Errorf("invalid quote action: %q", action)
+		}
+	}
+
+	var item as.AccessItem
+	if err := item.FromAccessQuote(as.AccessQuote{Type: as.Quote, Actions: actions}); err != nil {
+		return as.AccessItem{}, fmt.Errorf("failed to encode quote access: %w", err)
+	}
+	return item, nil
+}
+
+func NewOutgoingPaymentAccess(actions []as.AccessOutgoingActions, identifier string, limits *OutgoingPaymentLimits) (as.AccessItem, error) {
+	if len(actions) == 0 {
+		return as.AccessItem{}, fmt.Errorf("outgoing-payment access requires at least one action")
+	}
+	for _, action := range actions {
+		switch action {
+		case as.AccessOutgoingActionsCreate, as.AccessOutgoingActionsRead, as.AccessOutgoingActionsReadAll,
+			as.AccessOutgoingActionsList, as.AccessOutgoingActionsListAll:
+		default:
+			return as.AccessItem{}, fmt.Errorf("invalid outgoing-payment action: %q", action)
+		}
+	}
+	if identifier == "" {
+		return as.AccessItem{}, fmt.Errorf("outgoing-payment access requires an identifier")
+	}
+
+	access := as.AccessOutgoing{
+		Type:       as.OutgoingPayment,
+		Actions:    actions,
+		Identifier: identifier,
+	}
+
+	if limits != nil {
+		encoded, err := limits.encode()
+		if err != nil {
+			return as.AccessItem{}, err
+		}
+		access.Limits = &encoded
+	}
+
+	var item as.AccessItem
+	if err := item.FromAccessOutgoing(access); err != nil {
+		return as.AccessItem{}, fmt.Errorf("failed to encode outgoing-payment access: %w", err)
+	}
+	return item, nil
+}
+
+func (l *OutgoingPaymentLimits) encode() (as.LimitsOutgoing, error) {
+	var interval *as.Interval
+	if l.Interval != "" {
+		interval = &l.Interval
+	}
+	var receiver *as.Receiver
+	if l.Receiver != "" {
+		receiver = &l.Receiver
+	}
+
+	var limits as.LimitsOutgoing
+	var err error
+	switch {
+	case l.DebitAmount != nil && l.ReceiveAmount != nil:
+		return as.LimitsOutgoing{}, fmt.Errorf("debitAmount and receiveAmount limits are mutually exclusive")
+	case l.DebitAmount != nil:
+		err = limits.FromLimitsOutgoingDebitAmount(as.LimitsOutgoingDebitAmount{
+			DebitAmount: *l.DebitAmount,
+			Interval:    interval,
+			Receiver:    receiver,
+		})
+	case l.ReceiveAmount != nil:
+		err = limits.FromLimitsOutgoingReceiveAmount(as.LimitsOutgoingReceiveAmount{
+			ReceiveAmount: *l.ReceiveAmount,
+			Interval:      interval,
+			Receiver:      receiver,
+		})
+	default:
+		err = limits.FromLimitsOutgoingNoAmount(as.LimitsOutgoingNoAmount{
+			Interval: interval,
+			Receiver: receiver,
+		})
+	}
+	if err != nil {
+		return as.LimitsOutgoing{}, fmt.Errorf("failed to encode outgoing-payment limits: %w", err)
+	}
+	return limits, nil
+}
+
+// NewGrantRequest builds an access-token grant request from one or more
+// access items, e.g. those returned by NewIncomingPaymentAccess. The client
+// field is filled in by GrantService.Request. The result is checked with
+// ValidateGrantRequest.
+func NewGrantRequest(access []as.AccessItem, interact *as.InteractRequest) (as.GrantRequest, error) {
+	var body as.GrantRequest
+	if err := body.FromGrantRequestWithAccessToken(as.GrantRequestWithAccessToken{
+		AccessToken: as.AccessTokenRequest{Access: access},
+		Interact:    interact,
+	}); err != nil {
+		return as.GrantRequest{}, fmt.Errorf("failed to encode grant request: %w", err)
+	}
+
+	if err := ValidateGrantRequest(body); err != nil {
+		return as.GrantRequest{}, err
+	}
+	return body, nil
+}
+
+func NewIncomingPaymentGrantRequest(params IncomingPaymentGrantParams) (as.GrantRequest, error) {
+	item, err := NewIncomingPaymentAccess(params.Actions, params.Identifier)
+	if err != nil {
+		return as.GrantRequest{}, err
+	}
+	return NewGrantRequest([]as.AccessItem{item}, params.Interact)
+}
+
+func NewQuoteGrantRequest(params QuoteGrantParams) (as.GrantRequest, error) {
+	item, err := NewQuoteAccess(params.Actions)
+	if err != nil {
+		return as.GrantRequest{}, err
+	}
+	return NewGrantRequest([]as.AccessItem{item}, params.Interact)
+}
+
+func NewOutgoingPaymentGrantRequest(params OutgoingPaymentGrantParams) (as.GrantRequest, error) {
+	item, err := NewOutgoingPaymentAccess(params.Actions, params.Identifier, params.Limits)
+	if err != nil {
+		return as.GrantRequest{}, err
+	}
+	return NewGrantRequest([]as.AccessItem{item}, params.Interact)
+}
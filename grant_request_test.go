@@ -0,0 +1,178 @@
+package openpayments_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	openpayments "github.com/interledger/open-payments-go"
+	as "github.com/interledger/open-payments-go/generated/authserver"
+	"github.com/stretchr/testify/assert"
+)
+
+func decodeGrantRequest(t *testing.T, body as.GrantRequest) map[string]any {
+	t.Helper()
+	b, err := json.Marshal(body)
+	assert.NoError(t, err)
+	var decoded map[string]any
+	assert.NoError(t, json.Unmarshal(b, &decoded))
+	return decoded
+}
+
+func firstAccess(t *testing.T, decoded map[string]any) map[string]any {
+	t.Helper()
+	accessToken := decoded["access_token"].(map[string]any)
+	access := accessToken["access"].([]any)
+	assert.Len(t, access, 1)
+	return access[0].(map[string]any)
+}
+
+func TestNewIncomingPaymentGrantRequest(t *testing.T) {
+	body, err := openpayments.NewIncomingPaymentGrantRequest(openpayments.IncomingPaymentGrantParams{
+		Actions: []as.AccessIncomingActions{as.AccessIncomingActionsCreate, as.AccessIncomingActionsRead},
+	})
+	assert.NoError(t, err)
+
+	decoded := decodeGrantRequest(t, body)
+	assert.NotContains(t, decoded, "interact")
+	access := firstAccess(t, decoded)
+	assert.Equal(t, "incoming-payment", access["type"])
+	assert.Equal(t, []any{"create", "read"}, access["actions"])
+	assert.NotContains(t, access, "identifier")
+}
+
+func TestNewQuoteGrantRequest(t *testing.T) {
+	body, err := openpayments.NewQuoteGrantRequest(openpayments.QuoteGrantParams{
+		Actions: []as.AccessQuoteActions{as.Create, as.Read},
+	})
+	assert.NoError(t, err)
+
+	access := firstAccess(t, decodeGrantRequest(t, body))
+	assert.Equal(t, "quote", access["type"])
+	assert.Equal(t, []any{"create", "read"}, access["actions"])
+}
+
+func TestNewOutgoingPaymentGrantRequest(t *testing.T) {
+	body, err := openpayments.NewOutgoingPaymentGrantRequest(openpayments.OutgoingPaymentGrantParams{
+		Actions:    []as.AccessOutgoingActions{as.AccessOutgoingActionsCreate, as.AccessOutgoingActionsRead},
+		Identifier: walletAddress,
+		Limits: &openpayments.OutgoingPaymentLimits{
+			DebitAmount: &as.Amount{Value: "500", AssetCode: "USD", AssetScale: 2},
+			Interval:    "R/2024-01-01T00:00:00Z/P1M",
+		},
+		Interact: openpayments.NewRedirectInteract("https://client.example.com/finish", "client-nonce"),
+	})
+	assert.NoError(t, err)
+
+	decoded := decodeGrantRequest(t, body)
+	access := firstAccess(t, decoded)
+	assert.Equal(t, "outgoing-payment", access["type"])
+	assert.Equal(t, walletAddress, access["identifier"])
+
+	limits := access["limits"].(map[string]any)
+	assert.Equal(t, "R/2024-01-01T00:00:00Z/P1M", limits["interval"])
+	assert.Equal(t, "500", limits["debitAmount"].(map[string]any)["value"])
+	assert.NotContains(t, limits, "receiveAmount")
+
+	interact := decoded["interact"].(map[string]any)
+	assert.Equal(t, []any{"redirect"}, interact["start"])
+	finish := interact["finish"].(map[string]any)
+	assert.Equal(t, "redirect", finish["method"])
+	assert.Equal(t, "client-nonce", finish["nonce"])
+	assert.Equal(t, "https://client.example.com/finish", finish["uri"])
+}
+
+func TestNewGrantRequest_MultipleAccessItems(t *testing.T) {
+	incoming, err := openpayments.NewIncomingPaymentAccess([]as.AccessIncomingActions{as.AccessIncomingActionsRead}, walletAddress)
+	assert.NoError(t, err)
+	quote, err := openpayments.NewQuoteAccess([]as.AccessQuoteActions{as.Create})
+	assert.NoError(t, err)
+
+	body, err := openpayments.NewGrantRequest([]as.AccessItem{incoming, quote}, nil)
+	assert.NoError(t, err)
+
+	decoded := decodeGrantRequest(t, body)
+	access := decoded["access_token"].(map[string]any)["access"].([]any)
+	assert.Len(t, access, 2)
+	assert.Equal(t, walletAddress, access[0].(map[string]any)["identifier"])
+}
+
+func TestGrantRequestBuilders_InvalidCombinations(t *testing.T) {
+	amount := &as.Amount{Value: "500", AssetCode: "USD", AssetScale: 2}
+
+	tests := []struct {
+		name  string
+		build func() (as.GrantRequest, error)
+	}{
+		{
+			name: "incoming payment without actions",
+			build: func() (as.GrantRequest, error) {
+				return openpayments.NewIncomingPaymentGrantRequest(openpayments.IncomingPaymentGrantParams{})
+			},
+		},
+		{
+			name: "incoming payment with unknown action",
+			build: func() (as.GrantRequest, error) {
+				return openpayments.NewIncomingPaymentGrantRequest(openpayments.IncomingPaymentGrantParams{
+					Actions: []as.AccessIncomingActions{"delete"},
+				})
+			},
+		},
+		{
+			name: "quote without actions",
+			build: func() (as.GrantRequest, error) {
+				return openpayments.NewQuoteGrantRequest(openpayments.QuoteGrantParams{})
+			},
+		},
+		{
+			name: "outgoing payment without interaction",
+			build: func() (as.GrantRequest, error) {
+				return openpayments.NewOutgoingPaymentGrantRequest(openpayments.OutgoingPaymentGrantParams{
+					Actions:    []as.AccessOutgoingActions{as.AccessOutgoingActionsCreate},
+					Identifier: walletAddress,
+				})
+			},
+		},
+		{
+			name: "outgoing payment without identifier",
+			build: func() (as.GrantRequest, error) {
+				return openpayments.NewOutgoingPaymentGrantRequest(openpayments.OutgoingPaymentGrantParams{
+					Actions:  []as.AccessOutgoingActions{as.AccessOutgoingActionsCreate},
+					Interact: openpayments.NewRedirectInteract("", ""),
+				})
+			},
+		},
+		{
+			name: "outgoing payment with debit and receive amount limits",
+			build: func() (as.GrantRequest, error) {
+				return openpayments.NewOutgoingPaymentGrantRequest(openpayments.OutgoingPaymentGrantParams{
+					Actions:    []as.AccessOutgoingActions{as.AccessOutgoingActionsCreate},
+					Identifier: walletAddress,
+					Limits:     &openpayments.OutgoingPaymentLimits{DebitAmount: amount, ReceiveAmount: amount},
+					Interact:   openpayments.NewRedirectInteract("", ""),
+				})
+			},
+		},
+		{
+			name: "interact without start methods",
+			build: func() (as.GrantRequest, error) {
+				return openpayments.NewIncomingPaymentGrantRequest(openpayments.IncomingPaymentGrantParams{
+					Actions:  []as.AccessIncomingActions{as.AccessIncomingActionsCreate},
+					Interact: &as.InteractRequest{},
+				})
+			},
+		},
+		{
+			name: "no access items",
+			build: func() (as.GrantRequest, error) {
+				return openpayments.NewGrantRequest(nil, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.build()
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package openpayments
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	as "github.com/interledger/open-payments-go/generated/authserver"
+)
+
+var (
+	ErrGrantNotFound = errors.New("grant not found")
+)
+
+// GrantStore persists grants so continuations and access tokens survive
+// restarts. Grants are keyed by their continue URI.
+//
+// When configured with WithGrantStore, the grant and token services keep the
+// store up to date: grants are saved on Request and Continue, their access
+// token is replaced on Rotate and removed on Revoke, and Cancel deletes them.
+type GrantStore interface {
+	Save(ctx context.Context, grant Grant) error
+	Load(ctx context.Context, continueURI string) (Grant, error) // Returns ErrGrantNotFound if missing.
+	// LoadByManageURL returns the grant whose access token has manageURL, or
+	// ErrGrantNotFound.
+	LoadByManageURL(ctx context.Context, manageURL string) (Grant, error)
+	Delete(ctx context.Context, continueURI string) error
+	List(ctx context.Context) ([]Grant, error)
+}
+
+type MemoryGrantStore struct {
+	mu       sync.Mutex
+	grants   map[string]Grant
+	byManage map[string]string // Access token manage URL to continue URI.
+}
+
+func NewMemoryGrantStore() *MemoryGrantStore {
+	return &MemoryGrantStore{grants: make(map[string]Grant), byManage: make(map[string]string)}
+}
+
+func (s *MemoryGrantStore) Save(_ context.Context, grant Grant) error {
+	if grant.Continue.Uri == "" {
+		return fmt.Errorf("grant has no continue URI")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unindex(grant.Continue.Uri)
+	s.grants[grant.Continue.Uri] = grant
+	if grant.AccessToken != nil && grant.AccessToken.Manage != "" {
+		s.byManage[grant.AccessToken.Manage] = grant.Continue.Uri
+	}
+	return nil
+}
+
+// unindex removes the manage URL of the stored grant with continueURI from
+// the index. s.mu must be held.
+func (s *MemoryGrantStore) unindex(continueURI string) {
+	previous, ok := s.grants[continueURI]
+	if !ok || previous.AccessToken == nil {
+		return
+	}
+	if s.byManage[previous.AccessToken.Manage] == continueURI {
+		delete(s.byManage, previous.AccessToken.Manage)
+	}
+}
+
+func (s *MemoryGrantStore) Load(_ context.Context, continueURI string) (Grant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	grant, ok := s.grants[continueURI]
+	if !ok {
+		return Grant{}, ErrGrantNotFound
+	}
+	return grant, nil
+}
+
+func (s *MemoryGrantStore) LoadByManageURL(_ context.Context, manageURL string) (Grant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	grant, ok := s.grants[s.byManage[manageURL]]
+	if !ok {
+		return Grant{}, ErrGrantNotFound
+	}
+	return grant, nil
+}
+
+func (s *MemoryGrantStore) Delete(_ context.Context, continueURI string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.unindex(continueURI)
+	delete(s.grants, continueURI)
+	return nil
+}
+
+func (s *MemoryGrantStore) List(_ context.Context) ([]Grant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	grants := make([]Grant, 0, len(s.grants))
+	for _, grant := range s.grants {
+		grants = append(grants, grant)
+	}
+	sort.Slice(grants, func(i, j int) bool {
+		return grants[i].Continue.Uri < grants[j].Continue.Uri
+	})
+	return grants, nil
+}
+
+const (
+	grantFileExt      = ".grant"
+	grantTokenFileExt = ".token"
+)
+
+// FileGrantStore stores each grant in its own file in a directory, encrypted
+// with AES-256-GCM using a caller-supplied 32 byte key. Grants with an access
+// token also get an index file, named by the hash of the token's manage URL,
+// holding the name of the grant file.
+type FileGrantStore struct {
+	dir  string
+	aead cipher.AEAD
+	mu   sync.Mutex
+}
+
+func NewFileGrantStore(dir string, key []byte) (*FileGrantStore, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid key length %d: expected 32 bytes", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create grant store directory: %w", err)
+	}
+	return &FileGrantStore{dir: dir, aead: aead}, nil
+}
+
+func (s *FileGrantStore) fileName(continueURI string) string {
+	sum := sha256.Sum256([]byte(continueURI))
+	return hex.EncodeToString(sum[:]) + grantFileExt
+}
+
+func (s *FileGrantStore) tokenFileName(manageURL string) string {
+	sum := sha256.Sum256([]byte(manageURL))
+	return hex.EncodeToString(sum[:]) + grantTokenFileExt
+}
+
+func (s *FileGrantStore) Save(_ context.Context, grant Grant) error {
+	if grant.Continue.Uri == "" {
+		return fmt.Errorf("grant has no continue URI")
+	}
+
+	plaintext, err := json.Marshal(grant)
+	if err != nil {
+		return fmt.Errorf("failed to marshal grant: %w", err)
+	}
+
+	name := s.fileName(grant.Continue.Uri)
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	ciphertext := s.aead.Seal(nonce, nonce, plaintext, []byte(name))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.unindex(name); err != nil {
+		return err
+	}
+	if err := s.writeFile(name, ciphertext); err != nil {
+		return err
+	}
+	if grant.AccessToken != nil && grant.AccessToken.Manage != "" {
+		return s.writeFile(s.tokenFileName(grant.AccessToken.Manage), []byte(name))
+	}
+	return nil
+}
+
+// writeFile atomically replaces the file name in the store directory. s.mu
+// must be held.
+func (s *FileGrantStore) writeFile(name string, data []byte) error {
+	tmp, err := os.CreateTemp(s.dir, name+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to write grant: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write grant: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write grant: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write grant: %w", err)
+	}
+	return nil
+}
+
+func (s *FileGrantStore) Load(_ context.Context, continueURI string) (Grant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read(s.fileName(continueURI))
+}
+
+func (s *FileGrantStore) read(name string) (Grant, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return Grant{}, ErrGrantNotFound
+	}
+	if err != nil {
+		return Grant{}, fmt.Errorf("failed to read grant: %w", err)
+	}
+
+	nonceSize := s.aead.NonceSize()
+	if len(data) < nonceSize {
+		return Grant{}, fmt.Errorf("failed to decrypt grant %s: file too short", name)
+	}
+	plaintext, err := s.aead.Open(nil, data[:nonceSize], data[nonceSize:], []byte(name))
+	if err != nil {
+		return Grant{}, fmt.Errorf("failed to decrypt grant %s: %w", name, err)
+	}
+
+	var grant Grant
+	if err := json.Unmarshal(plaintext, &grant); err != nil {
+		return Grant{}, fmt.Errorf("failed to unmarshal grant %s: %w", name, err)
+	}
+	return grant, nil
+}
+
+func (s *FileGrantStore) LoadByManageURL(_ context.Context, manageURL string) (Grant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name, err := os.ReadFile(filepath.Join(s.dir, s.tokenFileName(manageURL)))
+	if errors.Is(err, os.ErrNotExist) {
+		return Grant{}, ErrGrantNotFound
+	}
+	if err != nil {
+		return Grant{}, fmt.Errorf("failed to read grant index: %w", err)
+	}
+	grant, err := s.read(filepath.Base(string(name)))
+	if err != nil {
+		return Grant{}, err
+	}
+	// The index may be stale if the token was replaced by another writer
+	if grant.AccessToken == nil || grant.AccessToken.Manage != manageURL {
+		return Grant{}, ErrGrantNotFound
+	}
+	return grant, nil
+}
+
+func (s *FileGrantStore) Delete(_ context.Context, continueURI string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := s.fileName(continueURI)
+	if err := s.unindex(name); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(s.dir, name))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete grant: %w", err)
+	}
+	return nil
+}
+
+// unindex removes the index file of the access token of the grant stored in
+// name, if it still points to that grant. A grant that can't be read is left
+// for LoadByManageURL to skip, so it can still be replaced or deleted. s.mu
+// must be held.
+func (s *FileGrantStore) unindex(name string) error {
+	previous, err := s.read(name)
+	if err != nil || previous.AccessToken == nil || previous.AccessToken.Manage == "" {
+		return nil
+	}
+	index := filepath.Join(s.dir, s.tokenFileName(previous.AccessToken.Manage))
+	if indexed, err := os.ReadFile(index); err != nil || string(indexed) != name {
+		return nil
+	}
+	if err := os.Remove(index); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete grant index: %w", err)
+	}
+	return nil
+}
+
+func (s *FileGrantStore) List(_ context.Context) ([]Grant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list grants: %w", err)
+	}
+
+	var grants []Grant
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), grantFileExt) {
+			continue
+		}
+		grant, err := s.read(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+	return grants, nil
+}
+
+// updateStoredAccessToken replaces the access token of the stored grant the
+// token with manageURL belongs to. A nil token removes it.
+func updateStoredAccessToken(ctx context.Context, store GrantStore, manageURL string, token *as.AccessToken) error {
+	grant, err := store.LoadByManageURL(ctx, manageURL)
+	if errors.Is(err, ErrGrantNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	grant.AccessToken = token
+	return store.Save(ctx, grant)
+}
@@ -0,0 +1,184 @@
+package openpayments_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	openpayments "github.com/interledger/open-payments-go"
+	as "github.com/interledger/open-payments-go/generated/authserver"
+	"github.com/stretchr/testify/assert"
+)
+
+var grantStoreKey = bytes.Repeat([]byte{7}, 32)
+
+func storedGrant(continueURI string, token string) openpayments.Grant {
+	return openpayments.Grant{
+		AccessToken: &as.AccessToken{Value: token, Manage: "https://auth.example.com/token/1"},
+		Continue: as.Continue{
+			Uri: continueURI,
+			AccessToken: struct {
+				Value string `json:"value"`
+			}{Value: "continue-token"},
+		},
+		InteractNonce: "client-nonce",
+		GrantEndpoint: "https://auth.example.com/",
+	}
+}
+
+func testGrantStore(t *testing.T, store openpayments.GrantStore) {
+	ctx := context.Background()
+
+	_, err := store.Load(ctx, "https://auth.example.com/continue/1")
+	assert.ErrorIs(t, err, openpayments.ErrGrantNotFound)
+
+	first := storedGrant("https://auth.example.com/continue/1", "token-1")
+	second := storedGrant("https://auth.example.com/continue/2", "token-2")
+	second.AccessToken.Manage = "https://auth.example.com/token/2"
+	assert.NoError(t, store.Save(ctx, first))
+	assert.NoError(t, store.Save(ctx, second))
+
+	loaded, err := store.Load(ctx, first.Continue.Uri)
+	assert.NoError(t, err)
+	assert.Equal(t, first, loaded)
+	loaded, err = store.LoadByManageURL(ctx, second.AccessToken.Manage)
+	assert.NoError(t, err)
+	assert.Equal(t, second, loaded)
+
+	first.AccessToken = &as.AccessToken{Value: "token-1b", Manage: "https://auth.example.com/token/1b"}
+	assert.NoError(t, store.Save(ctx, first))
+	grants, err := store.List(ctx)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []openpayments.Grant{first, second}, grants)
+	_, err = store.LoadByManageURL(ctx, "https://auth.example.com/token/1")
+	assert.ErrorIs(t, err, openpayments.ErrGrantNotFound)
+	loaded, err = store.LoadByManageURL(ctx, first.AccessToken.Manage)
+	assert.NoError(t, err)
+	assert.Equal(t, first, loaded)
+
+	assert.NoError(t, store.Delete(ctx, first.Continue.Uri))
+	assert.NoError(t, store.Delete(ctx, first.Continue.Uri))
+	_, err = store.Load(ctx, first.Continue.Uri)
+	assert.ErrorIs(t, err, openpayments.ErrGrantNotFound)
+	_, err = store.LoadByManageURL(ctx, first.AccessToken.Manage)
+	assert.ErrorIs(t, err, openpayments.ErrGrantNotFound)
+}
+
+func TestMemoryGrantStore(t *testing.T) {
+	testGrantStore(t, openpayments.NewMemoryGrantStore())
+}
+
+func TestFileGrantStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := openpayments.NewFileGrantStore(dir, grantStoreKey)
+	assert.NoError(t, err)
+	testGrantStore(t, store)
+}
+
+func TestFileGrantStore_EncryptsAtRest(t *testing.T) {
+	dir := t.TempDir()
+	store, err := openpayments.NewFileGrantStore(dir, grantStoreKey)
+	assert.NoError(t, err)
+
+	grant := storedGrant("https://auth.example.com/continue/1", "secret-access-token")
+	assert.NoError(t, store.Save(context.Background(), grant))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 2, "expected the grant and its token index")
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), "secret-access-token")
+		assert.NotContains(t, string(data), grant.AccessToken.Manage)
+	}
+
+	other, err := openpayments.NewFileGrantStore(dir, bytes.Repeat([]byte{8}, 32))
+	assert.NoError(t, err)
+	_, err = other.Load(context.Background(), grant.Continue.Uri)
+	assert.ErrorContains(t, err, "failed to decrypt grant")
+
+	_, err = openpayments.NewFileGrantStore(dir, []byte("short"))
+	assert.Error(t, err)
+}
+
+func TestGrant_VersionedJSON(t *testing.T) {
+	grant := storedGrant("https://auth.example.com/continue/1", "token-1")
+
+	data, err := json.Marshal(grant)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"version":1`)
+
+	var decoded openpayments.Grant
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, grant, decoded)
+
+	// auth server responses carry no version
+	var response openpayments.Grant
+	assert.NoError(t, json.Unmarshal([]byte(`{"access_token":{"value":"token-1","manage":"https://auth.example.com/token/1","access":[]},"continue":{"uri":"https://auth.example.com/continue/1","access_token":{"value":"continue-token"}}}`), &response))
+	assert.Equal(t, "token-1", response.AccessToken.Value)
+
+	err = json.Unmarshal([]byte(`{"version":2,"continue":{}}`), &response)
+	assert.ErrorContains(t, err, "unsupported grant serialization version 2")
+}
+
+func TestGrantStore_UpdatedByServices(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/":
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"access_token": as.AccessToken{Value: "token-1", Manage: server.URL + "/token/1"},
+				"continue":     map[string]any{"uri": server.URL + "/continue/1", "access_token": map[string]string{"value": "continue-token"}},
+			})
+		case strings.HasPrefix(r.URL.Path, "/token/") && r.Method == http.MethodPost:
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"access_token": as.AccessToken{Value: "token-2", Manage: server.URL + "/token/2"},
+			})
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	store := openpayments.NewMemoryGrantStore()
+	client, err := openpayments.NewAuthenticatedClient(walletAddress, pk, keyID,
+		openpayments.WithHTTPClientAuthed(server.Client()),
+		openpayments.WithGrantStore(store),
+	)
+	assert.NoError(t, err)
+
+	body, err := openpayments.NewIncomingPaymentGrantRequest(openpayments.IncomingPaymentGrantParams{
+		Actions: []as.AccessIncomingActions{as.AccessIncomingActionsCreate},
+	})
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	grant, err := client.Grant.Request(ctx, openpayments.GrantRequestParams{URL: server.URL + "/", RequestBody: body})
+	assert.NoError(t, err)
+
+	stored, err := store.Load(ctx, grant.Continue.Uri)
+	assert.NoError(t, err)
+	assert.Equal(t, "token-1", stored.AccessToken.Value)
+
+	_, err = client.Token.Rotate(ctx, openpayments.TokenRotateParams{URL: grant.AccessToken.Manage, AccessToken: grant.AccessToken.Value})
+	assert.NoError(t, err)
+	stored, err = store.Load(ctx, grant.Continue.Uri)
+	assert.NoError(t, err)
+	assert.Equal(t, "token-2", stored.AccessToken.Value)
+
+	assert.NoError(t, client.Token.Revoke(ctx, openpayments.TokenRevokeParams{URL: server.URL + "/token/2", AccessToken: "token-2"}))
+	stored, err = store.Load(ctx, grant.Continue.Uri)
+	assert.NoError(t, err)
+	assert.Nil(t, stored.AccessToken)
+
+	assert.NoError(t, client.Grant.Cancel(ctx, openpayments.GrantCancelParams{URL: grant.Continue.Uri, AccessToken: grant.Continue.AccessToken.Value}))
+	_, err = store.Load(ctx, grant.Continue.Uri)
+	assert.ErrorIs(t, err, openpayments.ErrGrantNotFound)
+}
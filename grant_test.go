@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	openpayments "github.com/interledger/open-payments-go"
 	as "github.com/interledger/open-payments-go/generated/authserver"
@@ -337,3 +338,109 @@ func TestGrantRequest_WithClientOverride(t *testing.T) {
 	assert.Equal(t, "key1", sentJwk["kid"])
 	assert.Equal(t, "EdDSA", sentJwk["alg"])
 }
+
+func TestGrantContinueUntilDone(t *testing.T) {
+	zero := 0
+	var tokens, interactRefs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, strings.TrimPrefix(r.Header.Get("Authorization"), "GNAP "))
+		var body as.ContinuationRequest
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.InteractRef != nil {
+			interactRefs = append(interactRefs, *body.InteractRef)
+		} else {
+			interactRefs = append(interactRefs, "")
+		}
+		switch len(tokens) {
+		case 1:
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"code":"too_fast","description":"Too fast"}}`))
+		case 2:
+			_ = json.NewEncoder(w).Encode(openpayments.Grant{
+				Continue: as.Continue{
+					Uri:  "http://" + r.Host + "/continue/2",
+					Wait: &zero,
+					AccessToken: struct {
+						Value string `json:"value"`
+					}{Value: "continue-token-2"},
+				},
+			})
+		default:
+			assert.Equal(t, "/continue/2", r.URL.Path)
+			_ = json.NewEncoder(w).Encode(openpayments.Grant{
+				AccessToken: &as.AccessToken{Value: "access-token"},
+				Continue:    as.Continue{Uri: "http://" + r.Host + "/continue/2"},
+			})
+		}
+	}))
+	defer server.Close()
+
+	client, err := openpayments.NewAuthenticatedClient(walletAddress, pk, keyID, openpayments.WithHTTPClientAuthed(server.Client()))
+	assert.NoError(t, err)
+
+	grant, err := client.Grant.ContinueUntilDone(context.Background(), openpayments.GrantContinueUntilDoneParams{
+		Continue: as.Continue{
+			Uri:  server.URL + "/continue/1",
+			Wait: &zero,
+			AccessToken: struct {
+				Value string `json:"value"`
+			}{Value: "continue-token-1"},
+		},
+		InteractRef:    "ref-1",
+		TooFastBackoff: time.Millisecond,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "access-token", grant.AccessToken.Value)
+	assert.Equal(t, []string{"continue-token-1", "continue-token-1", "continue-token-2"}, tokens)
+	// a too_fast error doesn't consume the interaction reference
+	assert.Equal(t, []string{"ref-1", "ref-1", ""}, interactRefs)
+}
+
+func TestGrantContinueUntilDone_Denied(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"error":{"code":"request_denied","description":"Grant finalized"}}`))
+	}))
+	defer server.Close()
+
+	client, err := openpayments.NewAuthenticatedClient(walletAddress, pk, keyID, openpayments.WithHTTPClientAuthed(server.Client()))
+	assert.NoError(t, err)
+
+	_, err = client.Grant.ContinueUntilDone(context.Background(), openpayments.GrantContinueUntilDoneParams{
+		Continue: as.Continue{
+			Uri: server.URL + "/continue/1",
+			AccessToken: struct {
+				Value string `json:"value"`
+			}{Value: "continue-token"},
+		},
+		DefaultWait: time.Millisecond,
+	})
+
+	var clientErr *openpayments.OpenPaymentsClientError
+	if !assert.ErrorAs(t, err, &clientErr) {
+		return
+	}
+	assert.Equal(t, string(as.RequestDenied), clientErr.Code)
+}
+
+func TestGrantContinueUntilDone_ContextCanceled(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	client, err := openpayments.NewAuthenticatedClient(walletAddress, pk, keyID, openpayments.WithHTTPClientAuthed(server.Client()))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err = client.Grant.ContinueUntilDone(ctx, openpayments.GrantContinueUntilDoneParams{
+		Continue: as.Continue{Uri: server.URL + "/continue/1"},
+	})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 0, calls)
+}
@@ -0,0 +1,243 @@
+package openpayments
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	as "github.com/interledger/open-payments-go/generated/authserver"
+)
+
+// GrantRequestViolation describes a single problem found in a grant request.
+type GrantRequestViolation struct {
+	Field   string // Path to the offending field, e.g. "access_token.access[0].limits".
+	Message string
+}
+
+// GrantRequestValidationError is returned when a grant request fails
+// client-side validation. It lists every violation that was found so callers
+// can surface them without a round trip to the auth server.
+type GrantRequestValidationError struct {
+	Violations []GrantRequestViolation
+}
+
+func (e *GrantRequestValidationError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = fmt.Sprintf("%s: %s", v.Field, v.Message)
+	}
+	return "invalid grant request: " + strings.Join(msgs, "; ")
+}
+
+func (e *GrantRequestValidationError) add(field string, format string, args ...any) {
+	e.Violations = append(e.Violations, GrantRequestViolation{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// grantRequestShape is a variant-agnostic view of as.GrantRequest. The
+// generated union types can't be inspected without knowing the variant.
+type grantRequestShape struct {
+	AccessToken *struct {
+		Access []json.RawMessage `json:"access"`
+	} `json:"access_token"`
+	Interact *as.InteractRequest `json:"interact"`
+	Subject  *as.Subject         `json:"subject"`
+}
+
+type accessItemShape struct {
+	Type       string                     `json:"type"`
+	Actions    []string                   `json:"actions"`
+	Identifier *string                    `json:"identifier"`
+	Limits     map[string]json.RawMessage `json:"limits"`
+}
+
+var accessActions = map[string][]string{
+	string(as.IncomingPayment): {
+		string(as.AccessIncomingActionsCreate), string(as.AccessIncomingActionsRead), string(as.AccessIncomingActionsReadAll),
+		string(as.AccessIncomingActionsList), string(as.AccessIncomingActionsListAll), string(as.AccessIncomingActionsComplete),
+	},
+	string(as.Quote): {
+		string(as.Create), string(as.Read), string(as.ReadAll),
+	},
+	string(as.OutgoingPayment): {
+		string(as.AccessOutgoingActionsCreate), string(as.AccessOutgoingActionsRead), string(as.AccessOutgoingActionsReadAll),
+		string(as.AccessOutgoingActionsList), string(as.AccessOutgoingActionsListAll),
+	},
+}
+
+// ValidateGrantRequest checks a grant request for problems the auth server
+// would reject. It returns a *GrantRequestValidationError listing every
+// violation, or nil if the request is valid.
+func ValidateGrantRequest(body as.GrantRequest) error {
+	return validateGrantRequest(body, false)
+}
+
+func validateGrantRequest(body as.GrantRequest, directedIdentity bool) error {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("invalid grant request body: %w", err)
+	}
+
+	var shape grantRequestShape
+	if err := json.Unmarshal(raw, &shape); err != nil {
+		return fmt.Errorf("invalid grant request body: %w", err)
+	}
+
+	verr := &GrantRequestValidationError{}
+
+	if shape.AccessToken == nil && shape.Subject == nil {
+		verr.add("access_token", "either access_token or subject is required")
+	}
+	if shape.AccessToken == nil && shape.Subject != nil && shape.Interact == nil {
+		verr.add("interact", "subject grant requests require interaction")
+	}
+
+	if shape.AccessToken != nil {
+		if len(shape.AccessToken.Access) == 0 {
+			verr.add("access_token.access", "at least one access item is required")
+		}
+		for i, rawItem := range shape.AccessToken.Access {
+			validateAccessItem(verr, fmt.Sprintf("access_token.access[%d]", i), rawItem, shape.Interact != nil)
+		}
+	}
+
+	if shape.Interact != nil {
+		validateInteract(verr, shape.Interact)
+		if directedIdentity {
+			verr.add("client.jwk", "directed identity is only valid for non-interactive grant requests")
+		}
+	}
+
+	if len(verr.Violations) > 0 {
+		return verr
+	}
+	return nil
+}
+
+func validateAccessItem(verr *GrantRequestValidationError, path string, rawItem json.RawMessage, interactive bool) {
+	var item accessItemShape
+	if err := json.Unmarshal(rawItem, &item); err != nil {
+		verr.add(path, "malformed access item: %v", err)
+		return
+	}
+
+	allowed, ok := accessActions[item.Type]
+	if !ok {
+		verr.add(path+".type", "unknown access type %q", item.Type)
+		return
+	}
+
+	if len(item.Actions) == 0 {
+		verr.add(path+".actions", "at least one action is required")
+	}
+	for j, action := range item.Actions {
+		if !slices.Contains(allowed, action) {
+			verr.add(fmt.Sprintf("%s.actions[%d]", path, j), "invalid %s action %q", item.Type, action)
+		}
+	}
+
+	if item.Type != string(as.OutgoingPayment) {
+		if item.Limits != nil {
+			verr.add(path+".limits", "limits are only allowed for outgoing-payment access")
+		}
+		return
+	}
+
+	if item.Identifier == nil || *item.Identifier == "" {
+		verr.add(path+".identifier", "identifier is required for outgoing-payment access")
+	}
+	if !interactive {
+		verr.add(path, "outgoing-payment access requires an interactive grant request")
+	}
+
+	if item.Limits != nil {
+		_, hasDebit := item.Limits["debitAmount"]
+		_, hasReceive := item.Limits["receiveAmount"]
+		if hasDebit && hasReceive {
+			verr.add(path+".limits", "debitAmount and receiveAmount are mutually exclusive")
+		}
+		if rawInterval, ok := item.Limits["interval"]; ok {
+			var interval string
+			if err := json.Unmarshal(rawInterval, &interval); err != nil {
+				verr.add(path+".limits.interval", "interval must be a string")
+			} else if err := validateRepeatingInterval(interval); err != nil {
+				verr.add(path+".limits.interval", "%v", err)
+			}
+		}
+	}
+}
+
+func validateInteract(verr *GrantRequestValidationError, interact *as.InteractRequest) {
+	if len(interact.Start) == 0 {
+		verr.add("interact.start", "at least one start method is required")
+	}
+	for i, start := range interact.Start {
+		if start != as.InteractRequestStartRedirect {
+			verr.add(fmt.Sprintf("interact.start[%d]", i), "unsupported start method %q", start)
+		}
+	}
+
+	if interact.Finish == nil {
+		return
+	}
+	if interact.Finish.Method != as.InteractRequestFinishMethodRedirect {
+		verr.add("interact.finish.method", "unsupported finish method %q", interact.Finish.Method)
+	}
+	if u, err := url.Parse(interact.Finish.Uri); err != nil || !u.IsAbs() {
+		verr.add("interact.finish.uri", "uri must be an absolute URL")
+	}
+}
+
+var isoDuration = regexp.MustCompile(`^P(\d+([.,]\d+)?Y)?(\d+([.,]\d+)?M)?(\d+([.,]\d+)?W)?(\d+([.,]\d+)?D)?(T(\d+([.,]\d+)?H)?(\d+([.,]\d+)?M)?(\d+([.,]\d+)?S)?)?$`)
+
+var isoDateTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02",
+	"20060102T150405Z0700",
+	"20060102T150405Z",
+}
+
+func isISODuration(s string) bool {
+	return isoDuration.MatchString(s) && s != "P" && !strings.HasSuffix(s, "T")
+}
+
+func isISODateTime(s string) bool {
+	for _, layout := range isoDateTimeLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRepeatingInterval checks s is an ISO8601 repeating interval of the
+// form R[n]/<start>/<end>, R[n]/<start>/<duration> or R[n]/<duration>/<end>.
+func validateRepeatingInterval(s string) error {
+	parts := strings.Split(s, "/")
+	if len(parts) != 3 || !strings.HasPrefix(parts[0], "R") {
+		return fmt.Errorf("interval %q must have the form R[n]/<start>/<end or duration>", s)
+	}
+	for _, r := range parts[0][1:] {
+		if r < '0' || r > '9' {
+			return fmt.Errorf("interval %q has an invalid repetition count", s)
+		}
+	}
+
+	startIsDuration := isISODuration(parts[1])
+	endIsDuration := isISODuration(parts[2])
+	switch {
+	case startIsDuration && endIsDuration:
+		return fmt.Errorf("interval %q cannot consist of two durations", s)
+	case !startIsDuration && !isISODateTime(parts[1]):
+		return fmt.Errorf("interval %q has an invalid start %q", s, parts[1])
+	case !endIsDuration && !isISODateTime(parts[2]):
+		return fmt.Errorf("interval %q has an invalid end %q", s, parts[2])
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package openpayments_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	openpayments "github.com/interledger/open-payments-go"
+	as "github.com/interledger/open-payments-go/generated/authserver"
+	"github.com/stretchr/testify/assert"
+)
+
+func violationFields(t *testing.T, err error) []string {
+	t.Helper()
+	var verr *openpayments.GrantRequestValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected *GrantRequestValidationError, got %v", err)
+	}
+	fields := make([]string, len(verr.Violations))
+	for i, v := range verr.Violations {
+		fields[i] = v.Field
+	}
+	return fields
+}
+
+func outgoingAccessItem(t *testing.T, access as.AccessOutgoing) as.AccessItem {
+	t.Helper()
+	access.Type = as.OutgoingPayment
+	var item as.AccessItem
+	assert.NoError(t, item.FromAccessOutgoing(access))
+	return item
+}
+
+func grantRequestWithAccessToken(t *testing.T, access []as.AccessItem, interact *as.InteractRequest) as.GrantRequest {
+	t.Helper()
+	var body as.GrantRequest
+	assert.NoError(t, body.FromGrantRequestWithAccessToken(as.GrantRequestWithAccessToken{
+		AccessToken: as.AccessTokenRequest{Access: access},
+		Interact:    interact,
+	}))
+	return body
+}
+
+func TestValidateGrantRequest_Valid(t *testing.T) {
+	interval := "R/2024-01-01T00:00:00Z/P1M"
+	var limits as.LimitsOutgoing
+	assert.NoError(t, limits.FromLimitsOutgoingDebitAmount(as.LimitsOutgoingDebitAmount{
+		DebitAmount: as.Amount{Value: "100", AssetCode: "USD", AssetScale: 2},
+		Interval:    &interval,
+	}))
+
+	body := grantRequestWithAccessToken(t, []as.AccessItem{outgoingAccessItem(t, as.AccessOutgoing{
+		Actions:    []as.AccessOutgoingActions{as.AccessOutgoingActionsCreate},
+		Identifier: walletAddress,
+		Limits:     &limits,
+	})}, openpayments.NewRedirectInteract("https://client.example.com/finish", "nonce"))
+
+	assert.NoError(t, openpayments.ValidateGrantRequest(body))
+}
+
+func TestValidateGrantRequest_CollectsAllViolations(t *testing.T) {
+	var limits as.LimitsOutgoing
+	assert.NoError(t, limits.FromLimitsOutgoingDebitAmount(as.LimitsOutgoingDebitAmount{
+		DebitAmount: as.Amount{Value: "100", AssetCode: "USD", AssetScale: 2},
+	}))
+	assert.NoError(t, limits.MergeLimitsOutgoingReceiveAmount(as.LimitsOutgoingReceiveAmount{
+		ReceiveAmount: as.Amount{Value: "100", AssetCode: "USD", AssetScale: 2},
+	}))
+
+	var quote as.AccessItem
+	assert.NoError(t, quote.FromAccessQuote(as.AccessQuote{Type: as.Quote}))
+
+	body := grantRequestWithAccessToken(t, []as.AccessItem{
+		outgoingAccessItem(t, as.AccessOutgoing{
+			Actions: []as.AccessOutgoingActions{as.AccessOutgoingActionsCreate},
+			Limits:  &limits,
+		}),
+		quote,
+	}, nil)
+
+	err := openpayments.ValidateGrantRequest(body)
+	assert.ElementsMatch(t, []string{
+		"access_token.access[0].identifier",
+		"access_token.access[0]",
+		"access_token.access[0].limits",
+		"access_token.access[1].actions",
+	}, violationFields(t, err))
+}
+
+func TestValidateGrantRequest_Interval(t *testing.T) {
+	tests := []struct {
+		interval string
+		valid    bool
+	}{
+		{"R/2024-01-01T00:00:00Z/P1M", true},
+		{"R12/2024-01-01T00:00:00Z/P1W", true},
+		{"R/P1D/2024-01-01T00:00:00Z", true},
+		{"R/2024-01-01T00:00:00Z/2024-02-01T00:00:00Z", true},
+		{"R/2024-01-01/PT12H", true},
+		{"2024-01-01T00:00:00Z/P1M", false},
+		{"R/P1M/P1D", false},
+		{"R/not-a-date/P1M", false},
+		{"R/2024-01-01T00:00:00Z/P", false},
+		{"Rx/2024-01-01T00:00:00Z/P1M", false},
+		{"R/2024-01-01T00:00:00Z", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.interval, func(t *testing.T) {
+			interval := tt.interval
+			var limits as.LimitsOutgoing
+			assert.NoError(t, limits.FromLimitsOutgoingNoAmount(as.LimitsOutgoingNoAmount{Interval: &interval}))
+
+			body := grantRequestWithAccessToken(t, []as.AccessItem{outgoingAccessItem(t, as.AccessOutgoing{
+				Actions:    []as.AccessOutgoingActions{as.AccessOutgoingActionsCreate},
+				Identifier: walletAddress,
+				Limits:     &limits,
+			})}, openpayments.NewRedirectInteract("", ""))
+
+			err := openpayments.ValidateGrantRequest(body)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, []string{"access_token.access[0].limits.interval"}, violationFields(t, err))
+			}
+		})
+	}
+}
+
+func TestValidateGrantRequest_Interact(t *testing.T) {
+	incoming, err := openpayments.NewIncomingPaymentAccess([]as.AccessIncomingActions{as.AccessIncomingActionsCreate}, "")
+	assert.NoError(t, err)
+
+	interact := openpayments.NewRedirectInteract("/relative", "")
+	body := grantRequestWithAccessToken(t, []as.AccessItem{incoming}, interact)
+
+	assert.Equal(t, []string{"interact.finish.uri"}, violationFields(t, openpayments.ValidateGrantRequest(body)))
+
+	var subjectOnly as.GrantRequest
+	assert.NoError(t, subjectOnly.FromGrantRequestWithSubject(as.GrantRequestWithSubject{}))
+	assert.Equal(t, []string{"interact.start"}, violationFields(t, openpayments.ValidateGrantRequest(subjectOnly)))
+}
+
+func TestGrantRequest_ValidationFailsBeforeSending(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := openpayments.NewAuthenticatedClient(walletAddress, pk, keyID, openpayments.WithHTTPClientAuthed(server.Client()))
+	assert.NoError(t, err)
+
+	body := grantRequestWithAccessToken(t, []as.AccessItem{outgoingAccessItem(t, as.AccessOutgoing{
+		Actions:    []as.AccessOutgoingActions{as.AccessOutgoingActionsCreate},
+		Identifier: walletAddress,
+	})}, openpayments.NewRedirectInteract("", ""))
+
+	_, err = client.Grant.Request(context.Background(), openpayments.GrantRequestParams{
+		URL:            server.URL,
+		RequestBody:    body,
+		ClientOverride: &as.ClientDirectedIdentity{},
+	})
+
+	assert.Equal(t, []string{"client.jwk"}, violationFields(t, err))
+	assert.Equal(t, 0, calls)
+}
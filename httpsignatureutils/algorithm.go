@@ -0,0 +1,272 @@
+package httpsignatureutils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	_ "crypto/sha512" // registers SHA-384 and SHA-512 for crypto.Hash.New
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("unsupported signature algorithm")
+)
+
+// Algorithm names from the HTTP Signature Algorithms registry (RFC 9421,
+// section 6.2).
+const (
+	AlgorithmEd25519         = "ed25519"
+	AlgorithmECDSAP256SHA256 = "ecdsa-p256-sha256"
+	AlgorithmECDSAP384SHA384 = "ecdsa-p384-sha384"
+	AlgorithmRSAPSSSHA512    = "rsa-pss-sha512"
+	AlgorithmRSAV15SHA256    = "rsa-v1_5-sha256"
+	AlgorithmHMACSHA256      = "hmac-sha256"
+)
+
+// Algorithm creates and verifies signatures of signature bases.
+type Algorithm interface {
+	Name() string
+	Sign(signer crypto.Signer, base []byte) ([]byte, error)
+	Verify(key crypto.PublicKey, base []byte, signature []byte) error
+}
+
+var (
+	algorithmsMu sync.RWMutex
+	algorithms   = map[string]Algorithm{}
+)
+
+func init() {
+	for _, alg := range []Algorithm{
+		ed25519Algorithm{},
+		ecdsaAlgorithm{name: AlgorithmECDSAP256SHA256, curve: elliptic.P256(), hash: crypto.SHA256},
+		ecdsaAlgorithm{name: AlgorithmECDSAP384SHA384, curve: elliptic.P384(), hash: crypto.SHA384},
+		rsaPSSAlgorithm{},
+		rsaV15Algorithm{},
+		hmacAlgorithm{},
+	} {
+		RegisterAlgorithm(alg)
+	}
+}
+
+// RegisterAlgorithm adds alg to the algorithms signatures can be created and
+// verified with. It panics if an algorithm with the same name is already
+// registered, so the built-in algorithms can't be replaced.
+func RegisterAlgorithm(alg Algorithm) {
+	algorithmsMu.Lock()
+	defer algorithmsMu.Unlock()
+	if alg == nil {
+		panic("httpsignatureutils: nil algorithm")
+	}
+	if _, exists := algorithms[alg.Name()]; exists {
+		panic("httpsignatureutils: multiple registrations for algorithm " + alg.Name())
+	}
+	algorithms[alg.Name()] = alg
+}
+
+// LookupAlgorithm returns the registered algorithm called name.
+func LookupAlgorithm(name string) (Algorithm, error) {
+	algorithmsMu.RLock()
+	defer algorithmsMu.RUnlock()
+	alg, ok := algorithms[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, name)
+	}
+	return alg, nil
+}
+
+// AlgorithmForKey returns the algorithm used with a public key when none is
+// given. RSA keys default to rsa-pss-sha512.
+func AlgorithmForKey(key crypto.PublicKey) (Algorithm, error) {
+	switch k := key.(type) {
+	case ed25519.PublicKey:
+		return LookupAlgorithm(AlgorithmEd25519)
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return LookupAlgorithm(AlgorithmECDSAP256SHA256)
+		case elliptic.P384():
+			return LookupAlgorithm(AlgorithmECDSAP384SHA384)
+		}
+		return nil, fmt.Errorf("%w: ECDSA curve %s", ErrUnsupportedAlgorithm, k.Curve.Params().Name)
+	case *rsa.PublicKey:
+		return LookupAlgorithm(AlgorithmRSAPSSSHA512)
+	case HMACKey:
+		return LookupAlgorithm(AlgorithmHMACSHA256)
+	}
+	return nil, fmt.Errorf("%w: key type %T", ErrUnsupportedAlgorithm, key)
+}
+
+// resolveAlgorithm returns the algorithm called name, or the one for key if
+// name is empty.
+func resolveAlgorithm(name string, key crypto.PublicKey) (Algorithm, error) {
+	if name != "" {
+		return LookupAlgorithm(name)
+	}
+	return AlgorithmForKey(key)
+}
+
+func keyTypeError(alg string, key crypto.PublicKey) error {
+	return fmt.Errorf("%w: %s can't be used with key type %T", ErrUnsupportedAlgorithm, alg, key)
+}
+
+func digest(h crypto.Hash, base []byte) []byte {
+	hh := h.New()
+	hh.Write(base)
+	return hh.Sum(nil)
+}
+
+type ed25519Algorithm struct{}
+
+func (ed25519Algorithm) Name() string { return AlgorithmEd25519 }
+
+func (a ed25519Algorithm) Sign(signer crypto.Signer, base []byte) ([]byte, error) {
+	if _, ok := signer.Public().(ed25519.PublicKey); !ok {
+		return nil, keyTypeError(a.Name(), signer.Public())
+	}
+	return signer.Sign(nil, base, crypto.Hash(0))
+}
+
+func (a ed25519Algorithm) Verify(key crypto.PublicKey, base []byte, signature []byte) error {
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return keyTypeError(a.Name(), key)
+	}
+	if len(signature) != ed25519.SignatureSize || !ed25519.Verify(pub, base, signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// ecdsaAlgorithm signatures are the fixed-size concatenation of r and s, not
+// the ASN.1 encoding crypto.Signer returns.
+type ecdsaAlgorithm struct {
+	name  string
+	curve elliptic.Curve
+	hash  crypto.Hash
+}
+
+func (a ecdsaAlgorithm) Name() string { return a.name }
+
+func (a ecdsaAlgorithm) size() int { return (a.curve.Params().BitSize + 7) / 8 }
+
+func (a ecdsaAlgorithm) Sign(signer crypto.Signer, base []byte) ([]byte, error) {
+	if pub, ok := signer.Public().(*ecdsa.PublicKey); !ok || pub.Curve != a.curve {
+		return nil, keyTypeError(a.name, signer.Public())
+	}
+	der, err := signer.Sign(rand.Reader, digest(a.hash, base), a.hash)
+	if err != nil {
+		return nil, err
+	}
+	var sig struct{ R, S *big.Int }
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("failed to decode ECDSA signature: %w", err)
+	}
+	out := make([]byte, 2*a.size())
+	sig.R.FillBytes(out[:a.size()])
+	sig.S.FillBytes(out[a.size():])
+	return out, nil
+}
+
+func (a ecdsaAlgorithm) Verify(key crypto.PublicKey, base []byte, signature []byte) error {
+	pub, ok := key.(*ecdsa.PublicKey)
+	if !ok || pub.Curve != a.curve {
+		return keyTypeError(a.name, key)
+	}
+	if len(signature) != 2*a.size() {
+		return ErrInvalidSignature
+	}
+	r := new(big.Int).SetBytes(signature[:a.size()])
+	s := new(big.Int).SetBytes(signature[a.size():])
+	if !ecdsa.Verify(pub, digest(a.hash, base), r, s) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+type rsaPSSAlgorithm struct{}
+
+// rsa-pss-sha512 uses a 64 byte salt (RFC 9421, section 3.3.1).
+var pssOptions = &rsa.PSSOptions{SaltLength: 64, Hash: crypto.SHA512}
+
+func (rsaPSSAlgorithm) Name() string { return AlgorithmRSAPSSSHA512 }
+
+func (a rsaPSSAlgorithm) Sign(signer crypto.Signer, base []byte) ([]byte, error) {
+	if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+		return nil, keyTypeError(a.Name(), signer.Public())
+	}
+	return signer.Sign(rand.Reader, digest(crypto.SHA512, base), pssOptions)
+}
+
+func (a rsaPSSAlgorithm) Verify(key crypto.PublicKey, base []byte, signature []byte) error {
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return keyTypeError(a.Name(), key)
+	}
+	if err := rsa.VerifyPSS(pub, crypto.SHA512, digest(crypto.SHA512, base), signature, pssOptions); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+type rsaV15Algorithm struct{}
+
+func (rsaV15Algorithm) Name() string { return AlgorithmRSAV15SHA256 }
+
+func (a rsaV15Algorithm) Sign(signer crypto.Signer, base []byte) ([]byte, error) {
+	if _, ok := signer.Public().(*rsa.PublicKey); !ok {
+		return nil, keyTypeError(a.Name(), signer.Public())
+	}
+	return signer.Sign(rand.Reader, digest(crypto.SHA256, base), crypto.SHA256)
+}
+
+func (a rsaV15Algorithm) Verify(key crypto.PublicKey, base []byte, signature []byte) error {
+	pub, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return keyTypeError(a.Name(), key)
+	}
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest(crypto.SHA256, base), signature); err != nil {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// HMACKey is the shared secret of hmac-sha256 signatures. It is both the
+// signing key and the key signatures are verified with.
+type HMACKey []byte
+
+type hmacAlgorithm struct{}
+
+func (hmacAlgorithm) Name() string { return AlgorithmHMACSHA256 }
+
+func (a hmacAlgorithm) Sign(signer crypto.Signer, base []byte) ([]byte, error) {
+	if _, ok := signer.Public().(HMACKey); !ok {
+		return nil, keyTypeError(a.Name(), signer.Public())
+	}
+	return signer.Sign(nil, base, crypto.Hash(0))
+}
+
+func (a hmacAlgorithm) Verify(key crypto.PublicKey, base []byte, signature []byte) error {
+	secret, ok := key.(HMACKey)
+	if !ok {
+		return keyTypeError(a.Name(), key)
+	}
+	if !hmac.Equal(hmacSHA256(secret, base), signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func hmacSHA256(secret []byte, message []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write(message)
+	return h.Sum(nil)
+}
@@ -0,0 +1,142 @@
+package httpsignatureutils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAlgorithms_SignAndValidate(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	p384, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	secret := []byte("a shared secret of reasonable length")
+
+	tests := []struct {
+		alg    string
+		signer Signer
+		key    crypto.PublicKey
+	}{
+		{AlgorithmEd25519, NewSigner(edKey, "k"), edKey.Public()},
+		{AlgorithmECDSAP256SHA256, NewSigner(p256, "k"), p256.Public()},
+		{AlgorithmECDSAP384SHA384, NewSigner(p384, "k"), p384.Public()},
+		{AlgorithmRSAPSSSHA512, NewSigner(rsaKey, "k"), rsaKey.Public()},
+		{AlgorithmRSAV15SHA256, NewSigner(rsaKey, "k"), rsaKey.Public()},
+		{AlgorithmHMACSHA256, NewHMACSigner(secret, "k"), HMACKey(secret)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "https://example.com/resource", strings.NewReader(`{}`))
+			req.Header.Set("Content-Digest", CreateContentHeaders([]byte(`{}`)).ContentDigest)
+			req.Header.Set("Content-Length", "2")
+			req.Header.Set("Content-Type", "application/json")
+
+			opts := SignOptions{Request: req, Signer: tt.signer}
+			if tt.alg == AlgorithmRSAV15SHA256 {
+				opts.Algorithm = tt.alg
+			}
+			headers, err := CreateSignatureHeaders(opts)
+			if err != nil {
+				t.Fatalf("CreateSignatureHeaders returned error: %v", err)
+			}
+			if !strings.Contains(headers.SignatureInput, `alg="`+tt.alg+`"`) {
+				t.Errorf("expected alg %s in %s", tt.alg, headers.SignatureInput)
+			}
+			req.Header.Set("Signature", headers.SignatureHeader)
+			req.Header.Set("Signature-Input", headers.SignatureInput)
+
+			if err := ValidateSignature(NewValidationOptions(req, req.Header, tt.key)); err != nil {
+				t.Fatalf("expected signature to validate: %v", err)
+			}
+
+			restricted := NewValidationOptions(req, req.Header, tt.key)
+			restricted.Algorithms = []string{"some-other-alg"}
+			if err := ValidateSignature(restricted); !errors.Is(err, ErrUnsupportedAlgorithm) {
+				t.Errorf("expected ErrUnsupportedAlgorithm, got %v", err)
+			}
+
+			req.Header.Set("X-Tampered", "1")
+			req.Method = "PUT"
+			if err := ValidateSignature(NewValidationOptions(req, req.Header, tt.key)); !errors.Is(err, ErrInvalidSignature) {
+				t.Errorf("expected ErrInvalidSignature, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAlgorithms_KeyMismatch(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	req, _ := http.NewRequest("GET", "https://example.com/resource", nil)
+	if _, err := CreateSignatureHeaders(SignOptions{Request: req, Signer: NewSigner(p256, "k"), Algorithm: AlgorithmEd25519}); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("expected ErrUnsupportedAlgorithm when signing, got %v", err)
+	}
+
+	headers, err := CreateSignatureHeaders(SignOptions{Request: req, Signer: NewSigner(p256, "k")})
+	if err != nil {
+		t.Fatalf("CreateSignatureHeaders returned error: %v", err)
+	}
+	req.Header.Set("Signature", headers.SignatureHeader)
+	req.Header.Set("Signature-Input", headers.SignatureInput)
+	if err := ValidateSignature(NewValidationOptions(req, req.Header, edPub)); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("expected ErrUnsupportedAlgorithm when validating, got %v", err)
+	}
+}
+
+func TestRegisterAlgorithm_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a built-in algorithm again to panic")
+		}
+		if alg, _ := LookupAlgorithm(AlgorithmEd25519); alg != (ed25519Algorithm{}) {
+			t.Errorf("expected built-in ed25519 to stay registered, got %T", alg)
+		}
+	}()
+	RegisterAlgorithm(ed25519Algorithm{})
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	p256, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	sec1, _ := x509.MarshalECPrivateKey(p256)
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	p256PKCS8, _ := x509.MarshalPKCS8PrivateKey(p256)
+
+	tests := []struct {
+		name  string
+		block *pem.Block
+		alg   string
+	}{
+		{"SEC 1", &pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}, AlgorithmECDSAP256SHA256},
+		{"PKCS#1", &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, AlgorithmRSAPSSSHA512},
+		{"PKCS#8 Ed25519", &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}, AlgorithmEd25519},
+		{"PKCS#8 ECDSA", &pem.Block{Type: "PRIVATE KEY", Bytes: p256PKCS8}, AlgorithmECDSAP256SHA256},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := LoadPrivateKey(string(pem.EncodeToMemory(tt.block)))
+			if err != nil {
+				t.Fatalf("LoadPrivateKey returned error: %v", err)
+			}
+			alg, err := AlgorithmForKey(key.Public())
+			if err != nil || alg.Name() != tt.alg {
+				t.Errorf("expected %s, got %v (%v)", tt.alg, alg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package httpsignatureutils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrUnsupportedComponent = errors.New("unsupported signature component")
+)
+
+// message is the HTTP message a signature base is built from. For a response,
+// req is the request it answers, which components with the req parameter
+// refer to.
+type message struct {
+	req  *http.Request
+	resp *http.Response
+}
+
+func (m message) header() http.Header {
+	if m.resp != nil {
+		return m.resp.Header
+	}
+	return m.req.Header
+}
+
+// structuredFieldTypes are the types of the structured fields the sf
+// parameter is most likely used with. Other fields are parsed as a
+// dictionary, then a list, then an item.
+var structuredFieldTypes = map[string]string{
+	"content-digest":      "dictionary",
+	"repr-digest":         "dictionary",
+	"signature":           "dictionary",
+	"signature-input":     "dictionary",
+	"want-content-digest": "dictionary",
+	"accept-signature":    "dictionary",
+	"cache-status":        "list",
+	"priority":            "dictionary",
+}
+
+// componentValues returns the values of component in msg, per RFC 9421,
+// section 2. Every component has one value except @query-param, which has one
+// per occurrence of the parameter.
+func componentValues(msg message, component ComponentID) ([]string, error) {
+	name := component.Name
+	if name != strings.ToLower(name) {
+		return nil, fmt.Errorf("%w: component names must be lowercase: %s", ErrUnsupportedComponent, component)
+	}
+
+	for _, p := range component.Params {
+		switch p.Key {
+		case "req", "sf", "key", "name":
+		default:
+			return nil, fmt.Errorf("%w: unsupported component parameter: %s", ErrUnsupportedComponent, component)
+		}
+	}
+
+	if _, ok := component.Params.Get("req"); ok {
+		if msg.resp == nil {
+			return nil, fmt.Errorf("%w: req parameter on a request component: %s", ErrUnsupportedComponent, component)
+		}
+		if msg.req == nil {
+			return nil, fmt.Errorf("%w: no request for component %s", ErrUnsupportedComponent, component)
+		}
+		msg = message{req: msg.req}
+	}
+
+	if strings.HasPrefix(name, "@") {
+		return derivedComponentValues(msg, component)
+	}
+	if _, ok := component.Params.Get("name"); ok {
+		return nil, fmt.Errorf("%w: name parameter on a field component: %s", ErrUnsupportedComponent, component)
+	}
+	value, err := fieldValue(msg.header(), component)
+	if err != nil {
+		return nil, err
+	}
+	return []string{value}, nil
+}
+
+func derivedComponentValues(msg message, component ComponentID) ([]string, error) {
+	for _, p := range component.Params {
+		if p.Key == "sf" || p.Key == "key" || (p.Key == "name" && component.Name != "@query-param") {
+			return nil, fmt.Errorf("%w: unsupported component parameter: %s", ErrUnsupportedComponent, component)
+		}
+	}
+
+	if msg.resp != nil {
+		if component.Name != "@status" {
+			return nil, fmt.Errorf("%w: %s of a response requires the req parameter", ErrUnsupportedComponent, component)
+		}
+		return []string{strconv.Itoa(msg.resp.StatusCode)}, nil
+	}
+
+	req := msg.req
+	u := req.URL
+	switch component.Name {
+	case "@method":
+		return []string{req.Method}, nil
+	case "@target-uri":
+		target := *u
+		// An empty path is normalized to "/", before any query
+		if target.Path == "" {
+			target.Path = "/"
+		}
+		return []string{target.String()}, nil
+	case "@authority":
+		return []string{authority(req)}, nil
+	case "@scheme":
+		return []string{strings.ToLower(u.Scheme)}, nil
+	case "@request-target":
+		return []string{u.RequestURI()}, nil
+	case "@path":
+		path := u.EscapedPath()
+		if path == "" {
+			path = "/"
+		}
+		return []string{path}, nil
+	case "@query":
+		return []string{"?" + u.RawQuery}, nil
+	case "@query-param":
+		return queryParamValues(u, component)
+	case "@status":
+		return nil, fmt.Errorf("%w: @status on a request", ErrUnsupportedComponent)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnsupportedComponent, component)
+}
+
+// authority returns the lowercased host of req, without the port if it is the
+// scheme's default.
+func authority(req *http.Request) string {
+	host := req.URL.Host
+	if host == "" {
+		host = req.Host
+	}
+	host = strings.ToLower(host)
+	scheme := strings.ToLower(req.URL.Scheme)
+	if (scheme == "https" && strings.HasSuffix(host, ":443")) || (scheme == "http" && strings.HasSuffix(host, ":80")) {
+		host = host[:strings.LastIndex(host, ":")]
+	}
+	return host
+}
+
+func queryParamValues(u *url.URL, component ComponentID) ([]string, error) {
+	v, _ := component.Params.Get("name")
+	name, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: @query-param requires a name parameter", ErrUnsupportedComponent)
+	}
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query: %w", err)
+	}
+
+	// The name parameter holds the encoded name, while ParseQuery decodes it.
+	decoded, err := url.QueryUnescape(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid query parameter name %q", ErrUnsupportedComponent, name)
+	}
+	values, ok := query[decoded]
+	if !ok {
+		return nil, fmt.Errorf("%w: query parameter %s", ErrMissingRequiredHeader, name)
+	}
+
+	encoded := make([]string, len(values))
+	for i, value := range values {
+		encoded[i] = formURLEncode(value)
+	}
+	return encoded, nil
+}
+
+// formURLEncode percent-encodes s with the application/x-www-form-urlencoded
+// percent-encode set, using %20 for spaces as RFC 9421 requires.
+func formURLEncode(s string) string {
+	const hex = "0123456789ABCDEF"
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isAlpha(c) || isDigit(c) || c == '*' || c == '-' || c == '.' || c == '_' {
+			sb.WriteByte(c)
+			continue
+		}
+		sb.WriteByte('%')
+		sb.WriteByte(hex[c>>4])
+		sb.WriteByte(hex[c&0x0f])
+	}
+	return sb.String()
+}
+
+// fieldValue returns the canonical value of an HTTP field component: its
+// values trimmed and joined with ", ", or reserialized if sf or key is set.
+func fieldValue(header http.Header, component ComponentID) (string, error) {
+	raw := header.Values(component.Name)
+	if len(raw) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrMissingRequiredHeader, component.Name)
+	}
+	values := make([]string, len(raw))
+	for i, v := range raw {
+		values[i] = strings.TrimSpace(v)
+	}
+	value := strings.Join(values, ", ")
+
+	_, sf := component.Params.Get("sf")
+	keyParam, hasKey := component.Params.Get("key")
+	switch {
+	case hasKey:
+		key, ok := keyParam.(string)
+		if !ok {
+			return "", fmt.Errorf("%w: key parameter must be a string", ErrUnsupportedComponent)
+		}
+		dict, err := ParseDictionary(value)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse %s as a dictionary: %w", component.Name, err)
+		}
+		member, ok := dict.Get(key)
+		if !ok {
+			return "", fmt.Errorf("%w: %s has no member %q", ErrMissingRequiredHeader, component.Name, key)
+		}
+		var sb strings.Builder
+		if err := serializeMember(&sb, member); err != nil {
+			return "", err
+		}
+		return sb.String(), nil
+	case sf:
+		return reserializeStructuredField(component.Name, value)
+	}
+	return value, nil
+}
+
+func reserializeStructuredField(name string, value string) (string, error) {
+	switch structuredFieldTypes[name] {
+	case "dictionary":
+		dict, err := ParseDictionary(value)
+		if err != nil {
+			return "", err
+		}
+		return dict.Serialize()
+	case "list":
+		list, err := ParseList(value)
+		if err != nil {
+			return "", err
+		}
+		return list.Serialize()
+	}
+
+	if dict, err := ParseDictionary(value); err == nil {
+		return dict.Serialize()
+	}
+	if list, err := ParseList(value); err == nil {
+		return list.Serialize()
+	}
+	item, err := ParseItem(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s as a structured field: %w", name, err)
+	}
+	return item.Serialize()
+}
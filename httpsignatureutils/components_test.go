@@ -0,0 +1,116 @@
+package httpsignatureutils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Examples from RFC 9421, sections 2.1 and 2.2.
+func TestComponentValues(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://www.example.com:443/path?param=value&foo=bar&baz=batman&qux=", nil)
+	req.Header.Add("X-OWS-Header", "   Leading and trailing whitespace.   ")
+	req.Header.Add("Cache-Control", "max-age=60")
+	req.Header.Add("Cache-Control", "   must-revalidate")
+	req.Header.Set("Example-Dict", " a=1,    b=2;x=1;y=2,   c=(a   b   c)")
+
+	spaces, _ := http.NewRequest("GET", "http://example.com:8080/a%20b?var=this%20is%20a%20big%0Avalue&bar=with+plus+whitespace&bar=x", nil)
+	resp := &http.Response{StatusCode: 200, Header: http.Header{}, Request: req}
+
+	param := func(key string, value any) Params { return Params{{Key: key, Value: value}} }
+
+	tests := []struct {
+		msg       message
+		component ComponentID
+		expected  []string
+	}{
+		{message{req: req}, ComponentID{Name: "@method"}, []string{"POST"}},
+		{message{req: req}, ComponentID{Name: "@authority"}, []string{"www.example.com"}},
+		{message{req: req}, ComponentID{Name: "@scheme"}, []string{"https"}},
+		{message{req: req}, ComponentID{Name: "@path"}, []string{"/path"}},
+		{message{req: req}, ComponentID{Name: "@query"}, []string{"?param=value&foo=bar&baz=batman&qux="}},
+		{message{req: req}, ComponentID{Name: "@request-target"}, []string{"/path?param=value&foo=bar&baz=batman&qux="}},
+		{message{req: req}, ComponentID{Name: "@query-param", Params: param("baz", nil)}, nil},
+		{message{req: req}, ComponentID{Name: "@query-param", Params: param("name", "baz")}, []string{"batman"}},
+		{message{req: req}, ComponentID{Name: "@query-param", Params: param("name", "qux")}, []string{""}},
+		{message{req: spaces}, ComponentID{Name: "@query-param", Params: param("name", "var")}, []string{"this%20is%20a%20big%0Avalue"}},
+		{message{req: spaces}, ComponentID{Name: "@query-param", Params: param("name", "bar")}, []string{"with%20plus%20whitespace", "x"}},
+		{message{req: spaces}, ComponentID{Name: "@authority"}, []string{"example.com:8080"}},
+		{message{req: spaces}, ComponentID{Name: "@path"}, []string{"/a%20b"}},
+		{message{req: spaces}, ComponentID{Name: "@status"}, nil},
+		{message{req: req}, ComponentID{Name: "x-ows-header"}, []string{"Leading and trailing whitespace."}},
+		{message{req: req}, ComponentID{Name: "cache-control"}, []string{"max-age=60, must-revalidate"}},
+		{message{req: req}, ComponentID{Name: "Cache-Control"}, nil},
+		{message{req: req}, ComponentID{Name: "example-dict"}, []string{"a=1,    b=2;x=1;y=2,   c=(a   b   c)"}},
+		{message{req: req}, ComponentID{Name: "example-dict", Params: param("sf", true)}, []string{"a=1, b=2;x=1;y=2, c=(a b c)"}},
+		{message{req: req}, ComponentID{Name: "example-dict", Params: param("key", "a")}, []string{"1"}},
+		{message{req: req}, ComponentID{Name: "example-dict", Params: param("key", "b")}, []string{"2;x=1;y=2"}},
+		{message{req: req}, ComponentID{Name: "example-dict", Params: param("key", "c")}, []string{"(a b c)"}},
+		{message{req: req}, ComponentID{Name: "example-dict", Params: param("key", "d")}, nil},
+		{message{req: req}, ComponentID{Name: "x-missing"}, nil},
+		{message{req: req}, ComponentID{Name: "@method", Params: param("req", true)}, nil},
+		{message{req: req, resp: resp}, ComponentID{Name: "@status"}, []string{"200"}},
+		{message{req: req, resp: resp}, ComponentID{Name: "@method", Params: param("req", true)}, []string{"POST"}},
+		{message{req: req, resp: resp}, ComponentID{Name: "x-ows-header", Params: param("req", true)}, []string{"Leading and trailing whitespace."}},
+		{message{req: req, resp: resp}, ComponentID{Name: "x-ows-header"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.component.String(), func(t *testing.T) {
+			values, err := componentValues(tt.msg, tt.component)
+			if tt.expected == nil {
+				if err == nil {
+					t.Fatalf("expected error, got %q", values)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("componentValues returned error: %v", err)
+			}
+			if strings.Join(values, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("got %q, want %q", values, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCreateSignatureHeaders_Components(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ed25519 key: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "https://example.com/resource?id=1", nil)
+	req.Header.Set("X-Gateway", "gw-1")
+
+	headers, err := CreateSignatureHeaders(SignOptions{
+		Request:    req,
+		PrivateKey: priv,
+		KeyID:      "test-key",
+		Components: []ComponentID{
+			{Name: "@authority"},
+			{Name: "@query-param", Params: Params{{Key: "name", Value: "id"}}},
+			{Name: "x-gateway"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateSignatureHeaders returned error: %v", err)
+	}
+	if !strings.HasPrefix(headers.SignatureInput, `sig1=("@authority" "@query-param";name="id" "x-gateway")`) {
+		t.Errorf("unexpected Signature-Input: %s", headers.SignatureInput)
+	}
+
+	req.Header.Set("Signature", headers.SignatureHeader)
+	req.Header.Set("Signature-Input", headers.SignatureInput)
+	if err := ValidateSignature(NewValidationOptions(req, req.Header, pub)); err != nil {
+		t.Fatalf("expected signature to validate: %v", err)
+	}
+
+	req.Header.Set("X-Gateway", "gw-2")
+	if err := ValidateSignature(NewValidationOptions(req, req.Header, pub)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+package httpsignatureutils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Test messages and keys from RFC 9421, appendix B.
+
+const (
+	rfcRequestBody  = `{"hello": "world"}`
+	rfcResponseBody = `{"message": "good dog"}`
+
+	rfcRSAPSSPublicKey = `-----BEGIN PUBLIC KEY-----
+MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAr4tmm3r20Wd/PbqvP1s2
++QEtvpuRaV8Yq40gjUR8y2Rjxa6dpG2GXHbPfvMs8ct+Lh1GH45x28Rw3Ry53mm+
+oAXjyQ86OnDkZ5N8lYbggD4O3w6M6pAvLkhk95AndTrifbIFPNU8PPMO7OyrFAHq
+gDsznjPFmTOtCEcN2Z1FpWgchwuYLPL+Wokqltd11nqqzi+bJ9cvSKADYdUAAN5W
+Utzdpiy6LbTgSxP7ociU4Tn0g5I6aDZJ7A8Lzo0KSyZYoA485mqcO0GVAdVw9lq4
+aOT9v6d+nb4bnNkQVklLQ3fVAvJm+xdDOp9LCNCN48V2pnDOkFV6+U9nV5oyc6XI
+2wIDAQAB
+-----END PUBLIC KEY-----`
+	rfcECCP256PublicKey = `-----BEGIN PUBLIC KEY-----
+MFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAEqIVYZVLCrPZHGHjP17CTW0/+D9Lf
+w0EkjqF7xB4FivAxzic30tMM4GF+hR6Dxh71Z50VGGdldkkDXZCnTNnoXQ==
+-----END PUBLIC KEY-----`
+	rfcEd25519PrivateKey = "MC4CAQAwBQYDK2VwBCIEIJ+DYvh6SEqVTm50DFtMDoQikTmiCqirVv9mWG9qfSnF"
+	rfcSharedSecret      = "uzvJfB4u3N0Jy4T7NZ75MDVcr8zSTInedJtkgcu46YW4XByzNJjxBdtjUkdJPBtbmHhIDi6pcl8jsasjlTMtDQ=="
+)
+
+func rfcTestRequest() *http.Request {
+	req, _ := http.NewRequest("POST", "https://example.com/foo?param=Value&Pet=dog", strings.NewReader(rfcRequestBody))
+	req.Header.Set("Date", "Tue, 20 Apr 2021 02:07:55 GMT")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Digest", "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:")
+	req.Header.Set("Content-Length", "18")
+	return req
+}
+
+func rfcTestResponse() *http.Response {
+	resp := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(rfcResponseBody)),
+		Request:    rfcTestRequest(),
+	}
+	resp.Header.Set("Date", "Tue, 20 Apr 2021 02:07:56 GMT")
+	resp.Header.Set("Content-Type", "application/json")
+	resp.Header.Set("Content-Digest", "sha-512=:mEWXIS7MaLRuGgxOBdODa3xqM1XdEvxoYhvlCFJ41QJgJc4GTsPp29l5oGX69wWdXymyU0rjJuahq4l5aGgfLQ==:")
+	resp.Header.Set("Content-Length", "23")
+	return resp
+}
+
+func rfcKey(t *testing.T, name string) crypto.PublicKey {
+	t.Helper()
+	switch name {
+	case "test-key-rsa-pss", "test-key-ecc-p256":
+		keyPEM := rfcRSAPSSPublicKey
+		if name == "test-key-ecc-p256" {
+			keyPEM = rfcECCP256PublicKey
+		}
+		block, _ := pem.Decode([]byte(keyPEM))
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			t.Fatalf("failed to parse test key: %v", err)
+		}
+		return key
+	case "test-key-ed25519":
+		der, _ := base64.StdEncoding.DecodeString(rfcEd25519PrivateKey)
+		key, err := x509.ParsePKCS8PrivateKey(der)
+		if err != nil {
+			t.Fatalf("failed to parse test key: %v", err)
+		}
+		return key.(crypto.Signer).Public()
+	case "test-shared-secret":
+		secret, _ := base64.StdEncoding.DecodeString(rfcSharedSecret)
+		return HMACKey(secret)
+	}
+	return nil
+}
+
+func TestConformance_RFC9421(t *testing.T) {
+	tests := []struct {
+		name           string
+		response       bool
+		signatureInput string
+		signature      string // Verified with the test key named by keyid, if we have it.
+		base           []string
+	}{
+		{
+			name:           "2.5 signature base",
+			signatureInput: `sig1=("@method" "@authority" "@path" "content-digest" "content-length" "content-type");created=1618883475;keyid="test-key-rsa-pss"`,
+			base: []string{
+				`"@method": POST`,
+				`"@authority": example.com`,
+				`"@path": /foo`,
+				`"content-digest": sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:`,
+				`"content-length": 18`,
+				`"content-type": application/json`,
+				`"@signature-params": ("@method" "@authority" "@path" "content-digest" "content-length" "content-type");created=1618883475;keyid="test-key-rsa-pss"`,
+			},
+		},
+		{
+			name:           "B.2.1 minimal coverage",
+			signatureInput: `sig-b21=();created=1618884473;keyid="test-key-rsa-pss";nonce="b3k2pp5k7z-50gnwp.yemd"`,
+			signature:      `sig-b21=:d2pmTvmbncD3xQm8E9ZV2828BjQWGgiwAaw5bAkgibUopemLJcWDy/lkbbHAve4cRAtx31Iq786U7it++wgGxbtRxf8Udx7zFZsckzXaJMkA7ChG52eSkFxykJeNqsrWH5S+oxNFlD4dzVuwe8DhTSja8xxbR/Z2cOGdCbzR72rgFWhzx2VjBqJzsPLMIQKhO4DGezXehhWwE56YCE+O6c0mKZsfxVrogUvA4HELjVKWmAvtl6UnCh8jYzuVG5WSb/QEVPnP5TmcAnLH1g+s++v6d4s8m0gCw1fV5/SITLq9mhho8K3+7EPYTU8IU1bLhdxO5Nyt8C8ssinQ98Xw9Q==:`,
+			base: []string{
+				`"@signature-params": ();created=1618884473;keyid="test-key-rsa-pss";nonce="b3k2pp5k7z-50gnwp.yemd"`,
+			},
+		},
+		{
+			name:           "B.2.2 selective coverage",
+			signatureInput: `sig-b22=("@authority" "content-digest" "@query-param";name="Pet");created=1618884473;keyid="test-key-rsa-pss";tag="header-example"`,
+			signature:      `sig-b22=:LjbtqUbfmvjj5C5kr1Ugj4PmLYvx9wVjZvD9GsTT4F7GrcQEdJzgI9qHxICagShLRiLMlAJjtq6N4CDfKtjvuJyE5qH7KT8UCMkSowOB4+ECxCmT8rtAmj/0PIXxi0A0nxKyB09RNrCQibbUjsLS/2YyFYXEu4TRJQzRw1rLEuEfY17SARYhpTlaqwZVtR8NV7+4UKkjqpcAoFqWFQh62s7Cl+H2fjBSpqfZUJcsIk4N6wiKYd4je2U/lankenQ99PZfB4jY3I5rSV2DSBVkSFsURIjYErOs0tFTQosMTAoxk//0RoKUqiYY8Bh0aaUEb0rQl3/XaVe4bXTugEjHSw==:`,
+			base: []string{
+				`"@authority": example.com`,
+				`"content-digest": sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:`,
+				`"@query-param";name="Pet": dog`,
+				`"@signature-params": ("@authority" "content-digest" "@query-param";name="Pet");created=1618884473;keyid="test-key-rsa-pss";tag="header-example"`,
+			},
+		},
+		{
+			name:           "B.2.3 full coverage",
+			signatureInput: `sig-b23=("date" "@method" "@path" "@query" "@authority" "content-type" "content-digest" "content-length");created=1618884473;keyid="test-key-rsa-pss"`,
+			signature:      `sig-b23=:bbN8oArOxYoyylQQUU6QYwrTuaxLwjAC9fbY2F6SVWvh0yBiMIRGOnMYwZ/5MR6fb0Kh1rIRASVxFkeGt683+qRpRRU5p2voTp768ZrCUb38K0fUxN0O0iC59DzYx8DFll5GmydPxSmme9v6ULbMFkl+V5B1TP/yPViV7KsLNmvKiLJH1pFkh/aYA2HXXZzNBXmIkoQoLd7YfW91kE9o/CCoC1xMy7JA1ipwvKvfrs65ldmlu9bpG6A9BmzhuzF8Eim5f8ui9eH8LZH896+QIF61ka39VBrohr9iyMUJpvRX2Zbhl5ZJzSRxpJyoEZAFL2FUo5fTIztsDZKEgM4cUA==:`,
+			base: []string{
+				`"date": Tue, 20 Apr 2021 02:07:55 GMT`,
+				`"@method": POST`,
+				`"@path": /foo`,
+				`"@query": ?param=Value&Pet=dog`,
+				`"@authority": example.com`,
+				`"content-type": application/json`,
+				`"content-digest": sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:`,
+				`"content-length": 18`,
+				`"@signature-params": ("date" "@method" "@path" "@query" "@authority" "content-type" "content-digest" "content-length");created=1618884473;keyid="test-key-rsa-pss"`,
+			},
+		},
+		{
+			name:           "B.2.4 signing a response",
+			response:       true,
+			signatureInput: `sig-b24=("@status" "content-type" "content-digest" "content-length");created=1618884473;keyid="test-key-ecc-p256"`,
+			signature:      `sig-b24=:wNmSUAhwb5LxtOtOpNa6W5xj067m5hFrj0XQ4fvpaCLx0NKocgPquLgyahnzDnDAUy5eCdlYUEkLIj+32oiasw==:`,
+			base: []string{
+				`"@status": 200`,
+				`"content-type": application/json`,
+				`"content-digest": sha-512=:mEWXIS7MaLRuGgxOBdODa3xqM1XdEvxoYhvlCFJ41QJgJc4GTsPp29l5oGX69wWdXymyU0rjJuahq4l5aGgfLQ==:`,
+				`"content-length": 23`,
+				`"@signature-params": ("@status" "content-type" "content-digest" "content-length");created=1618884473;keyid="test-key-ecc-p256"`,
+			},
+		},
+		{
+			name:           "B.2.5 HMAC-SHA256",
+			signatureInput: `sig-b25=("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"`,
+			signature:      `sig-b25=:pxcQw6G3AjtMBQjwo8XzkZf/bws5LelbaMk5rGIGtE8=:`,
+			base: []string{
+				`"date": Tue, 20 Apr 2021 02:07:55 GMT`,
+				`"@authority": example.com`,
+				`"content-type": application/json`,
+				`"@signature-params": ("date" "@authority" "content-type");created=1618884473;keyid="test-shared-secret"`,
+			},
+		},
+		{
+			name:           "B.2.6 Ed25519",
+			signatureInput: `sig-b26=("date" "@method" "@path" "@authority" "content-type" "content-length");created=1618884473;keyid="test-key-ed25519"`,
+			signature:      `sig-b26=:wqcAqbmYJ2ji2glfAMaRy4gruYYnx2nEFN2HN6jrnDnQCK1u02Gb04v9EDgwUPiu4A0w6vuQv5lIp5WPpBKRCw==:`,
+			base: []string{
+				`"date": Tue, 20 Apr 2021 02:07:55 GMT`,
+				`"@method": POST`,
+				`"@path": /foo`,
+				`"@authority": example.com`,
+				`"content-type": application/json`,
+				`"content-length": 18`,
+				`"@signature-params": ("date" "@method" "@path" "@authority" "content-type" "content-length");created=1618884473;keyid="test-key-ed25519"`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dict, err := ParseDictionary(tt.signatureInput)
+			if err != nil {
+				t.Fatalf("failed to parse Signature-Input: %v", err)
+			}
+			params, err := signatureParamsFromInnerList(dict[0].Value.(InnerList))
+			if err != nil {
+				t.Fatalf("failed to read signature params: %v", err)
+			}
+			if serialized, _ := dict.Serialize(); serialized != tt.signatureInput {
+				t.Errorf("Signature-Input serialization mismatch:\n got: %s\nwant: %s", serialized, tt.signatureInput)
+			}
+
+			msg := message{req: rfcTestRequest()}
+			if tt.response {
+				resp := rfcTestResponse()
+				msg = message{req: resp.Request, resp: resp}
+			}
+			base, err := messageSignatureBase(msg, params)
+			if err != nil {
+				t.Fatalf("failed to create signature base: %v", err)
+			}
+			if want := strings.Join(tt.base, "\n"); base != want {
+				t.Errorf("signature base mismatch:\n got: %s\nwant: %s", base, want)
+			}
+
+			if tt.signature == "" {
+				return
+			}
+			keyID, _ := params.KeyID()
+			req := rfcTestRequest()
+			opts := NewValidationOptions(req, req.Header, rfcKey(t, keyID))
+			if tt.response {
+				resp := rfcTestResponse()
+				opts = NewResponseValidationOptions(resp, rfcKey(t, keyID))
+			}
+			opts.Headers.Set("Signature-Input", tt.signatureInput)
+			opts.Headers.Set("Signature", tt.signature)
+			// Some examples don't cover content-digest, which the RFC leaves
+			// to the application
+			opts.AllowUncoveredBody = true
+			if err := ValidateSignature(opts); err != nil {
+				t.Errorf("expected the RFC signature to validate: %v", err)
+			}
+		})
+	}
+}
+
+// Derived component examples from RFC 9421, section 2.2.
+func TestConformance_RFC9421DerivedComponents(t *testing.T) {
+	tests := []struct {
+		url       string
+		component string
+		value     string
+	}{
+		{"https://www.example.com/path?param=value", "@target-uri", "https://www.example.com/path?param=value"},
+		{"https://www.example.com/path?param=value", "@authority", "www.example.com"},
+		{"https://www.example.com/path?param=value", "@scheme", "https"},
+		{"https://www.example.com/path?param=value", "@request-target", "/path?param=value"},
+		{"https://www.example.com/path?param=value", "@path", "/path"},
+		{"https://www.example.com/path?param=value&foo=bar&baz=bat%2Dman", "@query", "?param=value&foo=bar&baz=bat%2Dman"},
+		{"https://www.example.com/path?queryString", "@query", "?queryString"},
+		{"https://www.example.com/path", "@query", "?"},
+		{"https://www.example.com", "@target-uri", "https://www.example.com/"},
+		{"https://www.example.com?param=value", "@target-uri", "https://www.example.com/?param=value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.component+" "+tt.url, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", tt.url, nil)
+			values, err := componentValues(message{req: req}, ComponentID{Name: tt.component})
+			if err != nil {
+				t.Fatalf("componentValues returned error: %v", err)
+			}
+			if len(values) != 1 || values[0] != tt.value {
+				t.Errorf("got %q, want %q", values, tt.value)
+			}
+		})
+	}
+}
+
+// Examples from RFC 9530, appendix B and section 2.
+func TestConformance_RFC9530(t *testing.T) {
+	tests := []struct {
+		body   string
+		digest string
+	}{
+		{rfcRequestBody, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:"},
+		{rfcRequestBody, "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:"},
+		{rfcRequestBody, "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:, sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:"},
+		{rfcResponseBody, "sha-512=:mEWXIS7MaLRuGgxOBdODa3xqM1XdEvxoYhvlCFJ41QJgJc4GTsPp29l5oGX69wWdXymyU0rjJuahq4l5aGgfLQ==:"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.digest, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "https://example.com/", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Content-Digest", tt.digest)
+			if err := VerifyContentDigest(req); err != nil {
+				t.Errorf("expected digest to verify: %v", err)
+			}
+		})
+	}
+
+	if got := CreateContentHeaders([]byte(rfcRequestBody)).ContentDigest; got != tests[1].digest {
+		t.Errorf("CreateContentHeaders digest mismatch:\n got: %s\nwant: %s", got, tests[1].digest)
+	}
+}
@@ -0,0 +1,160 @@
+package httpsignatureutils
+
+import (
+	"crypto"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DiagnosticOptions select the signature to diagnose.
+type DiagnosticOptions struct {
+	Request  *http.Request
+	Response *http.Response // When set, its signature is diagnosed. Request is the request it answers.
+	Headers  http.Header    // The Signature and Signature-Input headers. Defaults to the message's headers.
+	Label    string         // Defaults to the first signature.
+
+	PublicKey    crypto.PublicKey // When set, the signature is verified with it.
+	ExpectedBase string           // When set, e.g. to the base a server logged, it is diffed with the base.
+}
+
+// ComponentValue is the value a covered component has in the signature base.
+type ComponentValue struct {
+	Component ComponentID
+	Values    []string
+	Err       error // Why the value could not be determined.
+}
+
+// BaseLineDiff is a line that differs between the signature base and the
+// expected base. Line is 1-based; a missing line is empty.
+type BaseLineDiff struct {
+	Line     int
+	Got      string
+	Expected string
+}
+
+// SignatureDiagnostics describe how a signature was, or would be, checked.
+type SignatureDiagnostics struct {
+	Label      string
+	Params     SignatureParams
+	Components []ComponentValue
+	Base       string
+	BaseErr    error // Why the base could not be built.
+
+	Algorithm string
+	Verified  bool
+	VerifyErr error // Why verification failed, if a public key was given.
+
+	Diff []BaseLineDiff
+}
+
+// DiagnoseSignature rebuilds the signature base of a signed message, the way
+// ValidateSignature does, and reports every step. It only fails if the
+// signature headers can't be parsed.
+func DiagnoseSignature(opts DiagnosticOptions) (*SignatureDiagnostics, error) {
+	msg := message{req: opts.Request, resp: opts.Response}
+	headers := opts.Headers
+	if headers == nil {
+		headers = msg.header()
+	}
+
+	sigs, err := ParseSignatures(
+		strings.Join(headers.Values("Signature-Input"), ", "),
+		strings.Join(headers.Values("Signature"), ", "),
+	)
+	if err != nil {
+		return nil, err
+	}
+	sig := sigs[0]
+	if opts.Label != "" {
+		found := false
+		for _, s := range sigs {
+			if s.Label == opts.Label {
+				sig, found = s, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: no signature labeled %q", ErrMissingSignatureInput, opts.Label)
+		}
+	}
+
+	d := &SignatureDiagnostics{Label: sig.Label, Params: sig.Params}
+	for _, component := range sig.Params.Components {
+		values, err := componentValues(msg, component)
+		d.Components = append(d.Components, ComponentValue{Component: component, Values: values, Err: err})
+	}
+	d.Base, d.BaseErr = messageSignatureBase(msg, sig.Params)
+
+	if opts.ExpectedBase != "" {
+		d.Diff = diffBase(d.Base, opts.ExpectedBase)
+	}
+
+	if opts.PublicKey == nil {
+		return d, nil
+	}
+	algName, _ := sig.Params.Alg()
+	alg, err := resolveAlgorithm(algName, opts.PublicKey)
+	switch {
+	case err != nil:
+		d.VerifyErr = err
+	case d.BaseErr != nil:
+		d.Algorithm, d.VerifyErr = alg.Name(), d.BaseErr
+	default:
+		d.Algorithm = alg.Name()
+		d.VerifyErr = alg.Verify(opts.PublicKey, []byte(d.Base), sig.Signature)
+		d.Verified = d.VerifyErr == nil
+	}
+	return d, nil
+}
+
+func diffBase(got string, expected string) []BaseLineDiff {
+	gotLines := strings.Split(got, "\n")
+	expectedLines := strings.Split(strings.ReplaceAll(expected, "\r\n", "\n"), "\n")
+
+	var diff []BaseLineDiff
+	for i := 0; i < len(gotLines) || i < len(expectedLines); i++ {
+		var g, e string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(expectedLines) {
+			e = expectedLines[i]
+		}
+		if g != e {
+			diff = append(diff, BaseLineDiff{Line: i + 1, Got: g, Expected: e})
+		}
+	}
+	return diff
+}
+
+// String formats the diagnostics for logs.
+func (d *SignatureDiagnostics) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "signature %q\n", d.Label)
+	for _, c := range d.Components {
+		if c.Err != nil {
+			fmt.Fprintf(&sb, "  %s: error: %v\n", c.Component, c.Err)
+			continue
+		}
+		for _, v := range c.Values {
+			fmt.Fprintf(&sb, "  %s: %s\n", c.Component, v)
+		}
+	}
+	if d.BaseErr != nil {
+		fmt.Fprintf(&sb, "base: error: %v\n", d.BaseErr)
+	} else {
+		fmt.Fprintf(&sb, "base:\n%s\n", d.Base)
+	}
+	if d.Algorithm != "" || d.VerifyErr != nil {
+		fmt.Fprintf(&sb, "verified with %s: %t", d.Algorithm, d.Verified)
+		if d.VerifyErr != nil {
+			fmt.Fprintf(&sb, " (%v)", d.VerifyErr)
+		}
+		sb.WriteString("\n")
+	}
+	for _, line := range d.Diff {
+		fmt.Fprintf(&sb, "line %d:\n  - %s\n  + %s\n", line.Line, line.Expected, line.Got)
+	}
+	return sb.String()
+}
@@ -0,0 +1,69 @@
+package httpsignatureutils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDiagnoseSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ed25519 key: %v", err)
+	}
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	req, _ := http.NewRequest("GET", "https://example.com/incoming-payments/1", nil)
+	req.Header.Set("Authorization", "GNAP token")
+	headers, err := CreateSignatureHeaders(SignOptions{Request: req, PrivateKey: priv, KeyID: "test-key"})
+	if err != nil {
+		t.Fatalf("failed to sign request: %v", err)
+	}
+	req.Header.Set("Signature", headers.SignatureHeader)
+	req.Header.Set("Signature-Input", headers.SignatureInput)
+
+	d, err := DiagnoseSignature(DiagnosticOptions{Request: req, PublicKey: pub})
+	if err != nil {
+		t.Fatalf("DiagnoseSignature returned error: %v", err)
+	}
+	if !d.Verified || d.Algorithm != AlgorithmEd25519 || d.Base != headers.SignatureBase {
+		t.Errorf("unexpected diagnostics:\n%s", d)
+	}
+	if len(d.Components) != 3 || d.Components[2].Values[0] != "GNAP token" {
+		t.Errorf("unexpected components: %+v", d.Components)
+	}
+	if keyID, _ := d.Params.KeyID(); keyID != "test-key" {
+		t.Errorf("unexpected keyid %q", keyID)
+	}
+
+	// The server saw a different target URI and a different token.
+	serverBase := strings.Replace(headers.SignatureBase, "https://example.com/", "http://example.com/", 1)
+	serverBase = strings.Replace(serverBase, "GNAP token", "GNAP other", 1)
+	req.Header.Set("Authorization", "GNAP other")
+	d, err = DiagnoseSignature(DiagnosticOptions{Request: req, PublicKey: otherPub, ExpectedBase: serverBase})
+	if err != nil {
+		t.Fatalf("DiagnoseSignature returned error: %v", err)
+	}
+	if d.Verified || !errors.Is(d.VerifyErr, ErrInvalidSignature) {
+		t.Errorf("expected verification to fail, got %v", d.VerifyErr)
+	}
+	if len(d.Diff) != 1 || d.Diff[0].Line != 2 || !strings.HasPrefix(d.Diff[0].Expected, `"@target-uri": http://`) {
+		t.Errorf("unexpected diff: %+v", d.Diff)
+	}
+
+	req.Header.Del("Authorization")
+	d, err = DiagnoseSignature(DiagnosticOptions{Request: req})
+	if err != nil {
+		t.Fatalf("DiagnoseSignature returned error: %v", err)
+	}
+	if !errors.Is(d.Components[2].Err, ErrMissingRequiredHeader) || d.BaseErr == nil {
+		t.Errorf("expected missing authorization to be reported:\n%s", d)
+	}
+
+	if _, err := DiagnoseSignature(DiagnosticOptions{Request: req, Label: "other"}); !errors.Is(err, ErrMissingSignatureInput) {
+		t.Errorf("expected ErrMissingSignatureInput, got %v", err)
+	}
+}
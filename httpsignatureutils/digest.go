@@ -0,0 +1,101 @@
+package httpsignatureutils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrMissingContentDigest       = errors.New("missing content digest")
+	ErrUnsupportedDigestAlgorithm = errors.New("unsupported content digest algorithm")
+	ErrContentDigestMismatch      = errors.New("content digest does not match body")
+)
+
+// digestAlgorithms are the RFC 9530 algorithms VerifyContentDigest checks.
+var digestAlgorithms = map[string]func([]byte) []byte{
+	"sha-512": func(b []byte) []byte { sum := sha512.Sum512(b); return sum[:] },
+	"sha-256": func(b []byte) []byte { sum := sha256.Sum256(b); return sum[:] },
+}
+
+// readBody reads a request or response body and replaces it so it can be
+// read again.
+func readBody(rc *io.ReadCloser) ([]byte, error) {
+	if *rc == nil || *rc == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(*rc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read body: %w", err)
+	}
+	(*rc).Close()
+	*rc = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// VerifyContentDigest hashes the request body and compares it with the
+// Content-Digest header (RFC 9530). Every sha-512 and sha-256 digest in the
+// header must match; other algorithms are ignored, but at least one supported
+// algorithm is required. The body is left readable.
+func VerifyContentDigest(req *http.Request) error {
+	if len(req.Header.Values("Content-Digest")) == 0 {
+		return ErrMissingContentDigest
+	}
+	body, err := readBody(&req.Body)
+	if err != nil {
+		return err
+	}
+	return verifyContentDigest(req.Header, body)
+}
+
+// VerifyResponseContentDigest is VerifyContentDigest for a response.
+func VerifyResponseContentDigest(resp *http.Response) error {
+	if len(resp.Header.Values("Content-Digest")) == 0 {
+		return ErrMissingContentDigest
+	}
+	body, err := readBody(&resp.Body)
+	if err != nil {
+		return err
+	}
+	return verifyContentDigest(resp.Header, body)
+}
+
+func verifyContentDigest(headers http.Header, body []byte) error {
+	header := headers.Values("Content-Digest")
+	if len(header) == 0 {
+		return ErrMissingContentDigest
+	}
+
+	digests, err := ParseDictionary(strings.Join(header, ", "))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrContentDigestMismatch, err)
+	}
+
+	checked := 0
+	for _, member := range digests {
+		hash, ok := digestAlgorithms[member.Key]
+		if !ok {
+			continue
+		}
+		item, _ := member.Value.(Item)
+		expected, ok := item.Value.([]byte)
+		if !ok {
+			return fmt.Errorf("%w: %s digest is not a byte sequence", ErrContentDigestMismatch, member.Key)
+		}
+		if subtle.ConstantTimeCompare(hash(body), expected) != 1 {
+			return fmt.Errorf("%w: %s", ErrContentDigestMismatch, member.Key)
+		}
+		checked++
+	}
+
+	if checked == 0 {
+		return ErrUnsupportedDigestAlgorithm
+	}
+	return nil
+}
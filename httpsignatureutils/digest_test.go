@@ -0,0 +1,98 @@
+package httpsignatureutils
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestVerifyContentDigest(t *testing.T) {
+	body := `{"hello": "world"}`
+	tests := []struct {
+		name   string
+		digest string
+		err    error
+	}{
+		{"sha-512", "sha-512=:WZDPaVn/7XgHaAy8pmojAkGWoRx2UFChF41A2svX+TaPm+AbwAgBWnrIiYllu7BNNyealdVLvRwEmTHWXvJwew==:", nil},
+		{"sha-256", "sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:", nil},
+		{"unknown algorithm alongside sha-256", "md5=:AAAA:, sha-256=:X48E9qOokqqrvdts8nOJRJN3OWDUoyWxBf7kbu9DBPE=:", nil},
+		{"missing", "", ErrMissingContentDigest},
+		{"unsupported", "sha-1=:AAAA:", ErrUnsupportedDigestAlgorithm},
+		{"mismatch", "sha-256=:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=:", ErrContentDigestMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "https://example.com/", strings.NewReader(body))
+			if tt.digest != "" {
+				req.Header.Set("Content-Digest", tt.digest)
+			}
+
+			err := VerifyContentDigest(req)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if tt.err == ErrMissingContentDigest {
+				return
+			}
+
+			remaining, _ := io.ReadAll(req.Body)
+			if string(remaining) != body {
+				t.Errorf("body was not restored, got %q", remaining)
+			}
+		})
+	}
+}
+
+func TestValidateSignature_TamperedBody(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ed25519 key: %v", err)
+	}
+
+	body := []byte(`{"amount":"100"}`)
+	req, _ := http.NewRequest("POST", "https://example.com/resource", bytes.NewReader(body))
+	contentHeaders := CreateContentHeaders(body)
+	req.Header.Set("Content-Digest", contentHeaders.ContentDigest)
+	req.Header.Set("Content-Length", contentHeaders.ContentLength)
+	req.Header.Set("Content-Type", contentHeaders.ContentType)
+
+	sigHeaders, err := CreateSignatureHeaders(SignOptions{Request: req, PrivateKey: priv, KeyID: "test-key"})
+	if err != nil {
+		t.Fatalf("failed to sign request: %v", err)
+	}
+	req.Header.Set("Signature", sigHeaders.SignatureHeader)
+	req.Header.Set("Signature-Input", sigHeaders.SignatureInput)
+
+	req.Body = io.NopCloser(strings.NewReader(`{"amount":"999"}`))
+	err = ValidateSignature(NewValidationOptions(req, req.Header, pub))
+	if !errors.Is(err, ErrContentDigestMismatch) {
+		t.Fatalf("expected ErrContentDigestMismatch, got %v", err)
+	}
+}
+
+func TestValidateSignature_BodyWithoutSignedDigest(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ed25519 key: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "https://example.com/resource", nil)
+	sigHeaders, err := CreateSignatureHeaders(SignOptions{Request: req, PrivateKey: priv, KeyID: "test-key"})
+	if err != nil {
+		t.Fatalf("failed to sign request: %v", err)
+	}
+	req.Header.Set("Signature", sigHeaders.SignatureHeader)
+	req.Header.Set("Signature-Input", sigHeaders.SignatureInput)
+
+	req.Body = io.NopCloser(strings.NewReader(`{"injected":true}`))
+	err = ValidateSignature(NewValidationOptions(req, req.Header, pub))
+	if !errors.Is(err, ErrMissingContentDigest) {
+		t.Fatalf("expected ErrMissingContentDigest, got %v", err)
+	}
+}
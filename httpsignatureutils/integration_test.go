@@ -3,6 +3,8 @@ package httpsignatureutils
 import (
 	"bytes"
 	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
 	"net/http"
 	"testing"
 )
@@ -32,7 +34,7 @@ func TestCreateAndValidateSignature(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to sign request: %v", err)
 	}
-	req.Header.Set("Signature", sigHeaders.Signature)
+	req.Header.Set("Signature", sigHeaders.SignatureHeader)
 	req.Header.Set("Signature-Input", sigHeaders.SignatureInput)
 
 	err = ValidateSignature(
@@ -44,17 +46,20 @@ func TestCreateAndValidateSignature(t *testing.T) {
 
 	// negative test - bad signature should fail
 	t.Run("InvalidSignature", func(t *testing.T) {
-		// flip bits of first byte
-		badSig := []byte(sigHeaders.Signature)
+		// flip bits of the first signature byte, keeping the header well-formed
+		badSig, err := base64.StdEncoding.DecodeString(sigHeaders.Signature)
+		if err != nil {
+			t.Fatalf("failed to decode signature: %v", err)
+		}
 		badSig[0] ^= 0xFF
 
-		req.Header.Set("Signature", string(badSig))
+		req.Header.Set("Signature", "sig1=:"+base64.StdEncoding.EncodeToString(badSig)+":")
 
 		err = ValidateSignature(
 			NewValidationOptions(req, req.Header, publicKey),
 		)
-		if err == nil {
-			t.Fatal("expected validation to fail with bad signature, but it passed")
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Fatalf("expected ErrInvalidSignature for a bad signature, got %v", err)
 		}
 	})
 }
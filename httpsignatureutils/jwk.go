@@ -1,15 +1,27 @@
-// TODO ts client has key and jwk files. everything ehre is unused so far but dumping for preservation
-// purposes. need to split these out if i actually end up using them.
 package httpsignatureutils
 
 import (
 	"crypto/ed25519"
-	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+
+	as "github.com/interledger/open-payments-go/generated/authserver"
+	was "github.com/interledger/open-payments-go/generated/walletaddressserver"
+)
+
+var (
+	ErrUnsupportedJWK = errors.New("unsupported JSON Web Key")
 )
 
+// JWK is an Ed25519 JSON Web Key.
+//
+// Deprecated: Use was.JsonWebKey, as returned by PublicKeyToJWK.
+// WalletAddressKey and JWKFromWalletAddressKey convert between the two.
 type JWK struct {
 	Kid string `json:"kid"`
 	X   string `json:"x"`
@@ -18,6 +30,28 @@ type JWK struct {
 	Crv string `json:"crv"`
 }
 
+// WalletAddressKey returns j as a wallet address JWK.
+func (j JWK) WalletAddressKey() was.JsonWebKey {
+	return was.JsonWebKey{
+		Alg: was.JsonWebKeyAlg(j.Alg),
+		Crv: was.JsonWebKeyCrv(j.Crv),
+		Kid: j.Kid,
+		Kty: was.JsonWebKeyKty(j.Kty),
+		X:   j.X,
+	}
+}
+
+// JWKFromWalletAddressKey returns a wallet address JWK as a JWK.
+func JWKFromWalletAddressKey(jwk was.JsonWebKey) JWK {
+	return JWK{
+		Kid: jwk.Kid,
+		X:   jwk.X,
+		Alg: string(jwk.Alg),
+		Kty: string(jwk.Kty),
+		Crv: string(jwk.Crv),
+	}
+}
+
 func GenerateNewPrivateKey() (string, error) {
 	// Generate a new Ed25519 key pair
 	_, privateKey, err := ed25519.GenerateKey(nil)
@@ -30,32 +64,136 @@ func GenerateNewPrivateKey() (string, error) {
 	return encodedKey, nil
 }
 
-// TODO: rm if unused
-func generateJSONWebKey(){
-	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
-	if err != nil {
-		fmt.Println("Error generating key:", err)
-		return
+// PublicKeyToJWK returns key as a wallet address JWK with keyID.
+func PublicKeyToJWK(key ed25519.PublicKey, keyID string) was.JsonWebKey {
+	use := was.Sig
+	return was.JsonWebKey{
+		Alg: was.EdDSA,
+		Crv: was.Ed25519,
+		Kid: keyID,
+		Kty: was.OKP,
+		Use: &use,
+		X:   base64.RawURLEncoding.EncodeToString(key),
 	}
+}
 
-	x := base64.RawURLEncoding.EncodeToString(publicKey)
+// PublicKeyToAuthServerJWK returns key as a JWK for grant requests, such as
+// the key of a directed identity client.
+func PublicKeyToAuthServerJWK(key ed25519.PublicKey, keyID string) as.JsonWebKey {
+	use := as.Sig
+	return as.JsonWebKey{
+		Alg: as.EdDSA,
+		Crv: as.Ed25519,
+		Kid: keyID,
+		Kty: as.OKP,
+		Use: &use,
+		X:   base64.RawURLEncoding.EncodeToString(key),
+	}
+}
 
-	jwk := JWK{
-		Kid: "keyid-123", // Replace with your key ID
-		X:   x,
-		Alg: "EdDSA",
-		Kty: "OKP",
-		Crv: "Ed25519",
+// PublicKeyFromJWK returns the Ed25519 public key of a wallet address JWK.
+func PublicKeyFromJWK(jwk was.JsonWebKey) (ed25519.PublicKey, error) {
+	var use string
+	if jwk.Use != nil {
+		use = string(*jwk.Use)
 	}
+	return publicKeyFromJWK(string(jwk.Kty), string(jwk.Crv), string(jwk.Alg), use, jwk.X)
+}
 
-	jwkJSON, err := json.MarshalIndent(jwk, "", "  ")
-	if err != nil {
-		fmt.Println("Error marshaling JWK:", err)
-		return
+// PublicKeyFromAuthServerJWK returns the Ed25519 public key of a grant
+// request JWK.
+func PublicKeyFromAuthServerJWK(jwk as.JsonWebKey) (ed25519.PublicKey, error) {
+	var use string
+	if jwk.Use != nil {
+		use = string(*jwk.Use)
 	}
+	return publicKeyFromJWK(string(jwk.Kty), string(jwk.Crv), string(jwk.Alg), use, jwk.X)
+}
 
-	fmt.Println(string(jwkJSON))
+func publicKeyFromJWK(kty, crv, alg, use, x string) (ed25519.PublicKey, error) {
+	if kty != "OKP" || crv != "Ed25519" {
+		return nil, fmt.Errorf("%w: key type %s/%s", ErrUnsupportedJWK, kty, crv)
+	}
+	if alg != "" && alg != "EdDSA" {
+		return nil, fmt.Errorf("%w: algorithm %s", ErrUnsupportedJWK, alg)
+	}
+	if use != "" && use != "sig" {
+		return nil, fmt.Errorf("%w: use %s", ErrUnsupportedJWK, use)
+	}
+	key, err := base64.RawURLEncoding.DecodeString(x)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: invalid Ed25519 public key", ErrUnsupportedJWK)
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// Thumbprint returns the RFC 7638 JWK thumbprint of key: the base64url SHA-256
+// hash of its required members in lexicographic order.
+func Thumbprint(key ed25519.PublicKey) string {
+	x := base64.RawURLEncoding.EncodeToString(key)
+	sum := sha256.Sum256([]byte(`{"crv":"Ed25519","kty":"OKP","x":"` + x + `"}`))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
 
-	privateKeyBase64 := base64.RawURLEncoding.EncodeToString(privateKey.Seed())
-	fmt.Println("Private Key (base64):", privateKeyBase64)
-}
\ No newline at end of file
+// NewJSONWebKeySet returns a key set holding keys.
+func NewJSONWebKeySet(keys ...was.JsonWebKey) was.JsonWebKeySet {
+	set := make([]was.JsonWebKey, len(keys))
+	copy(set, keys)
+	return was.JsonWebKeySet{Keys: &set}
+}
+
+// KeySetFromKeyRing returns the key set publishing every key in kr, including
+// keys that are being retired, so signatures made with them still verify.
+func KeySetFromKeyRing(kr *KeyRing) (was.JsonWebKeySet, error) {
+	var keys []was.JsonWebKey
+	for _, keyID := range kr.Keys() {
+		signer, err := kr.Get(keyID)
+		if err != nil {
+			// Retired since Keys was called
+			continue
+		}
+		pub, ok := signer.Public().(ed25519.PublicKey)
+		if !ok {
+			return was.JsonWebKeySet{}, fmt.Errorf("%w: key %s has type %T", ErrUnsupportedJWK, keyID, signer.Public())
+		}
+		keys = append(keys, PublicKeyToJWK(pub, keyID))
+	}
+	return NewJSONWebKeySet(keys...), nil
+}
+
+// KeySetSource returns the key set of the wallet address at walletAddressPath,
+// or false if no such wallet address is hosted.
+type KeySetSource func(r *http.Request, walletAddressPath string) (was.JsonWebKeySet, bool)
+
+// JWKSHandler serves the key sets of hosted wallet addresses at
+// <wallet address>/jwks.json.
+func JWKSHandler(source KeySetSource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		walletAddressPath, ok := strings.CutSuffix(r.URL.Path, "/jwks.json")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		set, ok := source(r, walletAddressPath)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if set.Keys == nil {
+			set = NewJSONWebKeySet()
+		}
+		body, err := json.Marshal(set)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body) // The client has gone if this fails
+	})
+}
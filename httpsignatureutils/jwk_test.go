@@ -0,0 +1,97 @@
+package httpsignatureutils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	was "github.com/interledger/open-payments-go/generated/walletaddressserver"
+)
+
+// Example from RFC 8037, appendix A.3.
+func TestThumbprint(t *testing.T) {
+	pub, _ := base64.RawURLEncoding.DecodeString("11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo")
+	if got := Thumbprint(pub); got != "kPrK_qmxVWaYVA9wwBF6Iuo3vVzz7TxHCTwXBygrS4k" {
+		t.Errorf("unexpected thumbprint: %s", got)
+	}
+}
+
+func TestPublicKeyJWKRoundTrip(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ed25519 key: %v", err)
+	}
+
+	key, err := PublicKeyFromJWK(PublicKeyToJWK(pub, "key-1"))
+	if err != nil || !key.Equal(pub) {
+		t.Fatalf("wallet address JWK round trip failed: %v", err)
+	}
+	key, err = PublicKeyFromJWK(JWKFromWalletAddressKey(PublicKeyToJWK(pub, "key-1")).WalletAddressKey())
+	if err != nil || !key.Equal(pub) {
+		t.Fatalf("deprecated JWK round trip failed: %v", err)
+	}
+	key, err = PublicKeyFromAuthServerJWK(PublicKeyToAuthServerJWK(pub, "key-1"))
+	if err != nil || !key.Equal(pub) {
+		t.Fatalf("auth server JWK round trip failed: %v", err)
+	}
+
+	bad := PublicKeyToJWK(pub, "key-1")
+	bad.Crv = "X25519"
+	if _, err := PublicKeyFromJWK(bad); !errors.Is(err, ErrUnsupportedJWK) {
+		t.Errorf("expected ErrUnsupportedJWK for X25519, got %v", err)
+	}
+	bad = PublicKeyToJWK(pub[:16], "key-1")
+	if _, err := PublicKeyFromJWK(bad); !errors.Is(err, ErrUnsupportedJWK) {
+		t.Errorf("expected ErrUnsupportedJWK for a short key, got %v", err)
+	}
+}
+
+func TestJWKSHandler(t *testing.T) {
+	pub1, priv1, _ := ed25519.GenerateKey(rand.Reader)
+	_, priv2, _ := ed25519.GenerateKey(rand.Reader)
+	kr := NewKeyRing(NewEd25519Signer(priv1, "key-1"), NewEd25519Signer(priv2, "key-2"))
+
+	handler := JWKSHandler(func(r *http.Request, walletAddressPath string) (was.JsonWebKeySet, bool) {
+		if walletAddressPath != "/alice" {
+			return was.JsonWebKeySet{}, false
+		}
+		set, err := KeySetFromKeyRing(kr)
+		return set, err == nil
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/alice/jwks.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var set was.JsonWebKeySet
+	if err := json.Unmarshal(rec.Body.Bytes(), &set); err != nil {
+		t.Fatalf("failed to decode key set: %v", err)
+	}
+	if set.Keys == nil || len(*set.Keys) != 2 {
+		t.Fatalf("expected 2 keys, got %+v", set)
+	}
+	key, err := PublicKeyFromJWK((*set.Keys)[0])
+	if err != nil || !key.Equal(pub1) || (*set.Keys)[0].Kid != "key-1" {
+		t.Errorf("unexpected first key: %+v (%v)", (*set.Keys)[0], err)
+	}
+
+	for _, path := range []string{"/bob/jwks.json", "/alice"} {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d", path, rec.Code)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("POST", "/alice/jwks.json", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405, got %d", rec.Code)
+	}
+}
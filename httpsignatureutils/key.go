@@ -1,15 +1,24 @@
 package httpsignatureutils
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/ed25519"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 )
 
+var (
+	ErrUnrecognizedKeyFormat = errors.New("unrecognized private key format")
+	ErrKeyPasswordRequired   = errors.New("private key is encrypted and no password was given")
+	ErrIncorrectKeyPassword  = errors.New("incorrect private key password")
+)
+
 func parseEd25519PEM(pemBytes []byte) (ed25519.PrivateKey, error) {
 	block, _ := pem.Decode(pemBytes)
 	if block == nil {
@@ -50,17 +59,164 @@ func LoadPEMKey(pemString string) (ed25519.PrivateKey, error) {
 	return parseEd25519PEM([]byte(pemString))
 }
 
-func LoadKey(input string) (ed25519.PrivateKey, error) {
-	// PEM file path
+// LoadKey loads an Ed25519 private key. See LoadPrivateKey for the accepted
+// inputs.
+func LoadKey(input string, opts ...LoadKeyOption) (ed25519.PrivateKey, error) {
+	key, err := LoadPrivateKey(input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("key is not an Ed25519 key")
+	}
+	return edKey, nil
+}
+
+// parsePrivateKeyPEM parses a PKCS#8, encrypted PKCS#8, SEC 1 (EC), PKCS#1
+// (RSA) or OpenSSH private key.
+func parsePrivateKeyPEM(pemBytes []byte, o loadKeyOptions) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse PEM block")
+	}
+	if strings.Contains(block.Headers["Proc-Type"], "ENCRYPTED") {
+		return nil, fmt.Errorf("legacy encrypted PEM keys are not supported, convert the key to encrypted PKCS#8")
+	}
+
+	var key any
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "OPENSSH PRIVATE KEY":
+		key, err = parseOpenSSHPrivateKey(block, o.password)
+	case "ENCRYPTED PRIVATE KEY":
+		key, err = parseEncryptedPKCS8PrivateKey(block.Bytes, o.password)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	if _, err := AlgorithmForKey(signer.Public()); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+// triedKeyFormats lists the inputs LoadPrivateKey accepts, for errors about
+// inputs it doesn't recognize.
+const triedKeyFormats = "file path, PEM (PKCS#8, encrypted PKCS#8, SEC 1, PKCS#1, OpenSSH), " +
+	"base64-encoded PEM, base64 or base64url Ed25519 seed (32 bytes) or key (64 bytes)"
+
+// LoadKeyOption configures LoadKey and LoadPrivateKey.
+type LoadKeyOption func(*loadKeyOptions)
+
+type loadKeyOptions struct {
+	password []byte
+}
+
+// WithKeyPassword sets the password of encrypted PKCS#8 and OpenSSH keys.
+func WithKeyPassword(password []byte) LoadKeyOption {
+	return func(o *loadKeyOptions) {
+		o.password = password
+	}
+}
+
+// LoadPrivateKey loads an Ed25519, ECDSA (P-256 or P-384) or RSA private key.
+// input is one of:
+//   - "env:NAME", to load the key from the environment variable NAME, which
+//     holds any of the other inputs
+//   - the path of a file holding a PEM key or a base64 Ed25519 seed or key
+//   - a PEM key: PKCS#8, SEC 1, PKCS#1 or OpenSSH. PKCS#8 and OpenSSH keys
+//     may be encrypted, see WithKeyPassword
+//   - a base64-encoded PEM key
+//   - a base64 or base64url Ed25519 seed (32 bytes) or seed and public key
+//     (64 bytes)
+func LoadPrivateKey(input string, opts ...LoadKeyOption) (crypto.Signer, error) {
+	var o loadKeyOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if name, ok := strings.CutPrefix(input, "env:"); ok {
+		value := os.Getenv(name)
+		if value == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", name)
+		}
+		key, err := loadPrivateKey(value, o)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key from environment variable %s: %w", name, err)
+		}
+		return key, nil
+	}
+	return loadPrivateKey(input, o)
+}
+
+func loadPrivateKey(input string, o loadKeyOptions) (crypto.Signer, error) {
 	if _, err := os.Stat(input); err == nil {
-		return LoadKeyFromFile(input)
+		fileBytes, err := os.ReadFile(input) // #nosec G304
+		if err != nil {
+			return nil, fmt.Errorf("could not load file: %w", err)
+		}
+		key, err := parseKeyData(string(fileBytes), o)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key file %s: %w", input, err)
+		}
+		return key, nil
+	}
+
+	key, err := parseKeyData(input, o)
+	// Base64 has no dots or tildes, so the input was most likely a path
+	if errors.Is(err, ErrUnrecognizedKeyFormat) && strings.ContainsAny(input, ".~") {
+		return nil, fmt.Errorf("%w (no file exists at %s)", err, input)
 	}
+	return key, err
+}
 
-	// Raw PEM string
-	if strings.HasPrefix(strings.TrimSpace(input), "-----BEGIN") {
-		return LoadPEMKey(input)
+func parseKeyData(input string, o loadKeyOptions) (crypto.Signer, error) {
+	trimmed := strings.TrimSpace(input)
+	if strings.HasPrefix(trimmed, "-----BEGIN") {
+		return parsePrivateKeyPEM([]byte(trimmed), o)
 	}
 
-	// Base64 Key
-	return LoadBase64Key(input)
+	if raw, ok := decodeBase64(trimmed); ok {
+		switch {
+		case bytes.HasPrefix(bytes.TrimSpace(raw), []byte("-----BEGIN")):
+			return parsePrivateKeyPEM(raw, o)
+		case len(raw) == ed25519.SeedSize || len(raw) == ed25519.PrivateKeySize:
+			return ed25519KeyFromRaw(raw)
+		}
+	}
+	return nil, fmt.Errorf("%w, tried: %s", ErrUnrecognizedKeyFormat, triedKeyFormats)
+}
+
+// decodeBase64 decodes s as padded or unpadded base64 or base64url.
+func decodeBase64(s string) ([]byte, bool) {
+	for _, enc := range []*base64.Encoding{
+		base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding,
+	} {
+		if raw, err := enc.DecodeString(s); err == nil {
+			return raw, true
+		}
+	}
+	return nil, false
+}
+
+// ed25519KeyFromRaw returns the Ed25519 key with a raw seed, or with a raw
+// seed followed by its public key.
+func ed25519KeyFromRaw(raw []byte) (ed25519.PrivateKey, error) {
+	key := ed25519.NewKeyFromSeed(raw[:ed25519.SeedSize])
+	if len(raw) == ed25519.PrivateKeySize && !bytes.Equal(raw[ed25519.SeedSize:], key.Public().(ed25519.PublicKey)) {
+		return nil, fmt.Errorf("Ed25519 public key doesn't match the seed")
+	}
+	return key, nil
 }
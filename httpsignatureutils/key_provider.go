@@ -0,0 +1,246 @@
+package httpsignatureutils
+
+import (
+	"bytes"
+	"crypto"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// KeyProvider supplies the signer for new signatures. It is asked on every
+// signing, so implementations can swap keys at any time. KeyRing is a
+// KeyProvider.
+type KeyProvider interface {
+	Active() (Signer, error)
+}
+
+type staticKeyProvider struct {
+	signer Signer
+}
+
+// NewStaticKeyProvider returns a provider that always supplies signer.
+func NewStaticKeyProvider(signer Signer) KeyProvider {
+	return staticKeyProvider{signer: signer}
+}
+
+func (p staticKeyProvider) Active() (Signer, error) {
+	return p.signer, nil
+}
+
+// NewEnvKeyProvider returns a provider supplying the key in the environment
+// variable name, loaded once like LoadPrivateKey("env:" + name).
+func NewEnvKeyProvider(name string, keyID string, opts ...LoadKeyOption) (KeyProvider, error) {
+	key, err := LoadPrivateKey("env:"+name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return NewStaticKeyProvider(NewSigner(key, keyID)), nil
+}
+
+// DefaultKeyReloadInterval is how often a FileKeyProvider checks its file for
+// changes by default.
+const DefaultKeyReloadInterval = 10 * time.Second
+
+// FileKeyProvider supplies the key in a file, reloading it when the file
+// changes, e.g. when a mounted Kubernetes secret is rotated. The file is
+// checked at most once per reload interval, when a signer is asked for. If
+// the new contents don't parse, the previous key is kept.
+type FileKeyProvider struct {
+	path      string
+	keyIDPath string
+	interval  time.Duration
+	loadOpts  []LoadKeyOption
+	onReload  func(err error)
+
+	mu          sync.Mutex
+	signer      Signer
+	key         crypto.Signer
+	keyID       string
+	contents    []byte
+	lastChecked time.Time
+	lastKey     fileStamp
+	lastKeyID   fileStamp
+	now         func() time.Time
+}
+
+// fileStamp is what a file is compared with to find out if it changed.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampOf(info os.FileInfo) fileStamp {
+	if info == nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}
+}
+
+func (s fileStamp) equal(other fileStamp) bool {
+	return s.modTime.Equal(other.modTime) && s.size == other.size
+}
+
+// FileKeyProviderOption configures a FileKeyProvider.
+type FileKeyProviderOption func(*FileKeyProvider)
+
+// WithReloadInterval sets how often the file is checked for changes. Zero
+// checks it on every signing.
+func WithReloadInterval(interval time.Duration) FileKeyProviderOption {
+	return func(p *FileKeyProvider) {
+		p.interval = interval
+	}
+}
+
+// WithReloadCallback calls onReload with nil when a changed file holds a new
+// key, or with the error when a changed file can't be loaded and the previous
+// key is kept. Changes that leave the key the same aren't reported.
+func WithReloadCallback(onReload func(err error)) FileKeyProviderOption {
+	return func(p *FileKeyProvider) {
+		p.onReload = onReload
+	}
+}
+
+// WithKeyIDFile reads the key ID from the file at path instead of using the
+// key ID given to NewFileKeyProvider, e.g. a kid file stored next to the key
+// in the same secret. It is reloaded with the key, so a rotation can change
+// both.
+func WithKeyIDFile(path string) FileKeyProviderOption {
+	return func(p *FileKeyProvider) {
+		p.keyIDPath = path
+	}
+}
+
+// WithKeyLoadOptions sets the options the key is loaded with, e.g. its
+// password.
+func WithKeyLoadOptions(opts ...LoadKeyOption) FileKeyProviderOption {
+	return func(p *FileKeyProvider) {
+		p.loadOpts = opts
+	}
+}
+
+// NewFileKeyProvider returns a provider supplying the key in the file at path
+// under keyID, or under the key ID read with WithKeyIDFile. The file may hold any key LoadPrivateKey accepts other than
+// another path or environment variable. It fails if the file can't be loaded.
+func NewFileKeyProvider(path string, keyID string, opts ...FileKeyProviderOption) (*FileKeyProvider, error) {
+	p := &FileKeyProvider{
+		path:     path,
+		keyID:    keyID,
+		interval: DefaultKeyReloadInterval,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.keyID = keyID
+	info, keyIDInfo, err := p.stat()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.load(info, keyIDInfo); err != nil {
+		return nil, err
+	}
+	p.lastChecked = p.now()
+	return p, nil
+}
+
+// Active returns the signer for the file's key, reloading the file first if
+// it changed.
+func (p *FileKeyProvider) Active() (Signer, error) {
+	p.mu.Lock()
+	var reloaded bool
+	var err error
+	if now := p.now(); now.Sub(p.lastChecked) >= p.interval {
+		p.lastChecked = now
+		reloaded, err = p.reload()
+	}
+	signer := p.signer
+	p.mu.Unlock()
+
+	if reloaded && p.onReload != nil {
+		p.onReload(err)
+	}
+	return signer, nil
+}
+
+// reload loads the files if their modification time or size changed, and
+// reports whether the key or key ID changed or couldn't be loaded. p.mu must
+// be held.
+func (p *FileKeyProvider) reload() (bool, error) {
+	info, keyIDInfo, err := p.stat()
+	if err != nil {
+		return true, err
+	}
+	if stampOf(info).equal(p.lastKey) && stampOf(keyIDInfo).equal(p.lastKeyID) {
+		return false, nil
+	}
+	return p.load(info, keyIDInfo)
+}
+
+// stat returns the file info of the key file and, if one is used, the key ID
+// file.
+func (p *FileKeyProvider) stat() (os.FileInfo, os.FileInfo, error) {
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load file: %w", err)
+	}
+	if p.keyIDPath == "" {
+		return info, nil, nil
+	}
+	keyIDInfo, err := os.Stat(p.keyIDPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load key ID file: %w", err)
+	}
+	return info, keyIDInfo, nil
+}
+
+// load reads and parses the files, keeping the current signer on failure, and
+// reports whether the key or key ID changed or couldn't be loaded. p.mu must
+// be held, except during construction.
+func (p *FileKeyProvider) load(info, keyIDInfo os.FileInfo) (bool, error) {
+	contents, err := os.ReadFile(p.path) // #nosec G304
+	if err != nil {
+		return true, fmt.Errorf("could not load file: %w", err)
+	}
+	keyID := p.keyID
+	if p.keyIDPath != "" {
+		kid, err := os.ReadFile(p.keyIDPath) // #nosec G304
+		if err != nil {
+			return true, fmt.Errorf("could not load key ID file: %w", err)
+		}
+		keyID = strings.TrimSpace(string(kid))
+	}
+	// Don't retry the same contents until the files change again
+	p.lastKey = stampOf(info)
+	p.lastKeyID = stampOf(keyIDInfo)
+	if keyID == "" {
+		return true, fmt.Errorf("key ID file %s is empty", p.keyIDPath)
+	}
+
+	key, rotated := p.key, false
+	if !bytes.Equal(contents, p.contents) {
+		var o loadKeyOptions
+		for _, opt := range p.loadOpts {
+			opt(&o)
+		}
+		parsed, err := parseKeyData(string(contents), o)
+		if err != nil {
+			return true, fmt.Errorf("failed to load key file %s: %w", p.path, err)
+		}
+		p.contents = contents
+		// The same key written differently, e.g. re-encoded, isn't a rotation
+		if current, ok := p.key.(interface{ Equal(crypto.PrivateKey) bool }); !ok || !current.Equal(parsed) {
+			key, rotated = parsed, true
+		}
+	}
+	if !rotated && keyID == p.keyID {
+		return false, nil
+	}
+	p.key = key
+	p.keyID = keyID
+	p.signer = NewSigner(key, keyID)
+	return true, nil
+}
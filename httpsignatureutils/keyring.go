@@ -0,0 +1,161 @@
+package httpsignatureutils
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrKeyNotFound = errors.New("key not found in key ring")
+	ErrNoActiveKey = errors.New("key ring has no active key")
+)
+
+type keyRingEntry struct {
+	signer   Signer
+	retireAt time.Time // Zero unless the key is being retired.
+}
+
+// KeyRing holds the signers for several key IDs of a wallet address and
+// selects the active one used for new signatures. The active key can be
+// swapped at any time; requests that already picked a signer keep using it.
+// Retired keys are removed once their grace period has passed.
+type KeyRing struct {
+	mu     sync.Mutex
+	keys   map[string]*keyRingEntry
+	order  []string
+	active string
+	now    func() time.Time
+}
+
+// NewKeyRing returns a key ring holding signers. The first signer is active.
+func NewKeyRing(signers ...Signer) *KeyRing {
+	kr := &KeyRing{
+		keys: make(map[string]*keyRingEntry),
+		now:  time.Now,
+	}
+	for _, signer := range signers {
+		kr.Add(signer)
+	}
+	return kr
+}
+
+// Add adds signer, replacing any key with the same key ID. The first key
+// added becomes active.
+func (kr *KeyRing) Add(signer Signer) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.add(signer)
+}
+
+func (kr *KeyRing) add(signer Signer) {
+	keyID := signer.KeyID()
+	if _, ok := kr.keys[keyID]; !ok {
+		kr.order = append(kr.order, keyID)
+	}
+	kr.keys[keyID] = &keyRingEntry{signer: signer}
+	if kr.active == "" {
+		kr.active = keyID
+	}
+}
+
+// SetActive makes the key with keyID the active key. If the key was being
+// retired, the retirement is cancelled.
+func (kr *KeyRing) SetActive(keyID string) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.prune()
+
+	entry, ok := kr.keys[keyID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	entry.retireAt = time.Time{}
+	kr.active = keyID
+	return nil
+}
+
+// Rotate adds signer, makes it active and retires the previously active key
+// after grace.
+func (kr *KeyRing) Rotate(signer Signer, grace time.Duration) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.prune()
+
+	previous := kr.active
+	kr.add(signer)
+	kr.active = signer.KeyID()
+	if previous != "" && previous != kr.active {
+		kr.keys[previous].retireAt = kr.now().Add(grace)
+	}
+}
+
+// Retire removes the key with keyID after grace. The active key can't be
+// retired.
+func (kr *KeyRing) Retire(keyID string, grace time.Duration) error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.prune()
+
+	entry, ok := kr.keys[keyID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	if keyID == kr.active {
+		return fmt.Errorf("cannot retire active key %s", keyID)
+	}
+	entry.retireAt = kr.now().Add(grace)
+	kr.prune()
+	return nil
+}
+
+// Active returns the signer for the active key.
+func (kr *KeyRing) Active() (Signer, error) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.prune()
+
+	entry, ok := kr.keys[kr.active]
+	if !ok {
+		return nil, ErrNoActiveKey
+	}
+	return entry.signer, nil
+}
+
+// Get returns the signer for keyID, including keys within their retirement
+// grace period.
+func (kr *KeyRing) Get(keyID string) (Signer, error) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.prune()
+
+	entry, ok := kr.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, keyID)
+	}
+	return entry.signer, nil
+}
+
+// Keys returns the key IDs in the ring in the order they were added.
+func (kr *KeyRing) Keys() []string {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.prune()
+	return append([]string(nil), kr.order...)
+}
+
+// prune removes keys whose grace period has passed. kr.mu must be held.
+func (kr *KeyRing) prune() {
+	now := kr.now()
+	kept := kr.order[:0]
+	for _, keyID := range kr.order {
+		entry := kr.keys[keyID]
+		if !entry.retireAt.IsZero() && !now.Before(entry.retireAt) {
+			delete(kr.keys, keyID)
+			continue
+		}
+		kept = append(kept, keyID)
+	}
+	kr.order = kept
+}
@@ -0,0 +1,70 @@
+package httpsignatureutils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestSigner(t *testing.T, keyID string) Signer {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ed25519 key: %v", err)
+	}
+	return NewEd25519Signer(priv, keyID)
+}
+
+func TestKeyRing_Rotate(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	kr := NewKeyRing(newTestSigner(t, "key-1"))
+	kr.now = func() time.Time { return now }
+
+	kr.Rotate(newTestSigner(t, "key-2"), time.Minute)
+
+	active, err := kr.Active()
+	if err != nil {
+		t.Fatalf("Active returned error: %v", err)
+	}
+	if active.KeyID() != "key-2" {
+		t.Errorf("expected key-2 to be active, got %s", active.KeyID())
+	}
+	if _, err := kr.Get("key-1"); err != nil {
+		t.Errorf("expected key-1 to be kept during grace period: %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := kr.Get("key-1"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected key-1 to be removed after grace period, got %v", err)
+	}
+	if !reflect.DeepEqual(kr.Keys(), []string{"key-2"}) {
+		t.Errorf("unexpected keys: %v", kr.Keys())
+	}
+}
+
+func TestKeyRing_SetActiveAndRetire(t *testing.T) {
+	kr := NewKeyRing(newTestSigner(t, "key-1"), newTestSigner(t, "key-2"))
+
+	if err := kr.Retire("key-1", 0); err == nil {
+		t.Error("expected error retiring the active key")
+	}
+	if err := kr.SetActive("missing"); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound, got %v", err)
+	}
+	if err := kr.SetActive("key-2"); err != nil {
+		t.Fatalf("SetActive returned error: %v", err)
+	}
+	if err := kr.Retire("key-1", 0); err != nil {
+		t.Fatalf("Retire returned error: %v", err)
+	}
+	if !reflect.DeepEqual(kr.Keys(), []string{"key-2"}) {
+		t.Errorf("unexpected keys: %v", kr.Keys())
+	}
+
+	if _, err := NewKeyRing().Active(); !errors.Is(err, ErrNoActiveKey) {
+		t.Errorf("expected ErrNoActiveKey, got %v", err)
+	}
+}
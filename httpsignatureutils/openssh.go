@@ -0,0 +1,35 @@
+package httpsignatureutils
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// parseOpenSSHPrivateKey parses a key in the format written by ssh-keygen,
+// decrypting it with password if it has a passphrase.
+func parseOpenSSHPrivateKey(block *pem.Block, password []byte) (any, error) {
+	data := pem.EncodeToMemory(block)
+	key, err := ssh.ParseRawPrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if len(password) == 0 {
+			return nil, ErrKeyPasswordRequired
+		}
+		key, err = ssh.ParseRawPrivateKeyWithPassphrase(data, password)
+		if errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, ErrIncorrectKeyPassword
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Ed25519 keys are returned by pointer, and their public half isn't checked
+	if edKey, ok := key.(*ed25519.PrivateKey); ok {
+		return ed25519KeyFromRaw(*edKey)
+	}
+	return key, nil
+}
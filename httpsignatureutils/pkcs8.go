@@ -0,0 +1,135 @@
+package httpsignatureutils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha1" // #nosec G505 -- PBKDF2 default PRF, not used for signatures
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var (
+	oidPBES2          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSHA512 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 11}
+	oidAES128CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC      = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// encryptedPrivateKeyInfo is the PKCS#8 encrypted key structure (RFC 5958,
+// section 3).
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// pbes2Params and pbkdf2Params are defined in RFC 8018, appendix A.
+type pbes2Params struct {
+	KeyDerivationFunc pkix.AlgorithmIdentifier
+	EncryptionScheme  pkix.AlgorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                      `asn1:"optional"`
+	PRF            pkix.AlgorithmIdentifier `asn1:"optional"`
+}
+
+// parseEncryptedPKCS8PrivateKey decrypts a PBES2 encrypted PKCS#8 key, as
+// written by openssl pkcs8 -topk8, using PBKDF2 and AES-CBC. Neither the
+// standard library nor x/crypto decrypts PKCS#8, so only the PBES2 structure
+// is parsed here.
+func parseEncryptedPKCS8PrivateKey(der []byte, password []byte) (any, error) {
+	if len(password) == 0 {
+		return nil, ErrKeyPasswordRequired
+	}
+
+	var info encryptedPrivateKeyInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, fmt.Errorf("invalid encrypted PKCS#8 key: %w", err)
+	}
+	if !info.Algorithm.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("unsupported key encryption %s, only PBES2 is supported", info.Algorithm.Algorithm)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(info.Algorithm.Parameters.FullBytes, &params); err != nil {
+		return nil, fmt.Errorf("invalid PBES2 parameters: %w", err)
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("unsupported key derivation function %s, only PBKDF2 is supported", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, fmt.Errorf("invalid PBKDF2 parameters: %w", err)
+	}
+
+	var prf func() hash.Hash
+	switch {
+	case len(kdf.PRF.Algorithm) == 0, kdf.PRF.Algorithm.Equal(oidHMACWithSHA1):
+		prf = sha1.New
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA256):
+		prf = sha256.New
+	case kdf.PRF.Algorithm.Equal(oidHMACWithSHA512):
+		prf = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported PBKDF2 PRF %s", kdf.PRF.Algorithm)
+	}
+
+	var keyLen int
+	switch scheme := params.EncryptionScheme.Algorithm; {
+	case scheme.Equal(oidAES128CBC):
+		keyLen = 16
+	case scheme.Equal(oidAES192CBC):
+		keyLen = 24
+	case scheme.Equal(oidAES256CBC):
+		keyLen = 32
+	default:
+		return nil, fmt.Errorf("unsupported key encryption scheme %s", scheme)
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("invalid AES-CBC parameters")
+	}
+	if kdf.IterationCount < 1 || len(info.EncryptedData) == 0 || len(info.EncryptedData)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("invalid encrypted PKCS#8 key")
+	}
+
+	block, err := aes.NewCipher(pbkdf2.Key(password, kdf.Salt, kdf.IterationCount, keyLen, prf))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+	plaintext := make([]byte, len(info.EncryptedData))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, info.EncryptedData)
+
+	// A wrong password shows up as bad padding or as plaintext that isn't DER
+	pad := int(plaintext[len(plaintext)-1])
+	if pad == 0 || pad > aes.BlockSize {
+		return nil, ErrIncorrectKeyPassword
+	}
+	for _, b := range plaintext[len(plaintext)-pad:] {
+		if int(b) != pad {
+			return nil, ErrIncorrectKeyPassword
+		}
+	}
+	plaintext = plaintext[:len(plaintext)-pad]
+	key, err := x509.ParsePKCS8PrivateKey(plaintext)
+	if err != nil {
+		var der asn1.RawValue
+		if rest, derErr := asn1.Unmarshal(plaintext, &der); derErr != nil || len(rest) > 0 {
+			return nil, ErrIncorrectKeyPassword
+		}
+		return nil, fmt.Errorf("invalid decrypted PKCS#8 key: %w", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,105 @@
+package httpsignatureutils
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// ReplayCache remembers signatures that have already been accepted.
+type ReplayCache interface {
+	// Seen reports whether the signature for keyID was recorded before and
+	// still valid. If not, it is recorded until expiresAt; a zero expiresAt
+	// keeps it until it is evicted. When the signature has a nonce, the nonce
+	// identifies it instead, so reusing a nonce counts as a replay.
+	Seen(keyID, nonce string, signature []byte, expiresAt time.Time) bool
+}
+
+// DefaultReplayCacheSize is the capacity of an LRUReplayCache created with a
+// size of zero or less.
+const DefaultReplayCacheSize = 10000
+
+// pruneStep is how many of the least recently recorded signatures are checked
+// for expiry when the cache is full, keeping inserts constant time.
+const pruneStep = 16
+
+type replayEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+// LRUReplayCache is an in-memory ReplayCache holding a bounded number of
+// signatures. Expired signatures are dropped first, then the least recently
+// recorded ones.
+type LRUReplayCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[string]*list.Element
+	order   *list.List // Front is the most recently recorded.
+	now     func() time.Time
+}
+
+// NewLRUReplayCache returns a replay cache holding up to size signatures.
+func NewLRUReplayCache(size int) *LRUReplayCache {
+	if size <= 0 {
+		size = DefaultReplayCacheSize
+	}
+	return &LRUReplayCache{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+func (c *LRUReplayCache) Seen(keyID, nonce string, signature []byte, expiresAt time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	key := keyID + "\x00s\x00" + string(signature)
+	if nonce != "" {
+		key = keyID + "\x00n\x00" + nonce
+	}
+	if el, ok := c.entries[key]; ok {
+		entry := el.Value.(*replayEntry)
+		if entry.expiresAt.IsZero() || now.Before(entry.expiresAt) {
+			return true
+		}
+		c.remove(el)
+	}
+
+	if c.order.Len() >= c.size {
+		c.prune(now)
+	}
+	for c.order.Len() >= c.size {
+		c.remove(c.order.Back())
+	}
+	c.entries[key] = c.order.PushFront(&replayEntry{key: key, expiresAt: expiresAt})
+	return false
+}
+
+// Len returns the number of signatures held.
+func (c *LRUReplayCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// prune drops expired signatures among the pruneStep least recently recorded.
+func (c *LRUReplayCache) prune(now time.Time) {
+	el := c.order.Back()
+	for i := 0; i < pruneStep && el != nil; i++ {
+		prev := el.Prev()
+		entry := el.Value.(*replayEntry)
+		if !entry.expiresAt.IsZero() && !now.Before(entry.expiresAt) {
+			c.remove(el)
+		}
+		el = prev
+	}
+}
+
+func (c *LRUReplayCache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*replayEntry).key)
+}
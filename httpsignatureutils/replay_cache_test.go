@@ -0,0 +1,161 @@
+package httpsignatureutils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLRUReplayCache(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := NewLRUReplayCache(2)
+	cache.now = func() time.Time { return now }
+
+	if cache.Seen("key-1", "", []byte{1}, now.Add(time.Minute)) {
+		t.Fatal("expected first signature to be unseen")
+	}
+	if !cache.Seen("key-1", "", []byte{1}, now.Add(time.Minute)) {
+		t.Error("expected reused signature to be seen")
+	}
+	if cache.Seen("key-2", "", []byte{1}, now.Add(time.Minute)) {
+		t.Error("expected same signature under another key ID to be unseen")
+	}
+
+	// A third signature evicts the least recently recorded one.
+	cache.Seen("key-1", "", []byte{3}, time.Time{})
+	if cache.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", cache.Len())
+	}
+	if cache.Seen("key-1", "", []byte{1}, now.Add(time.Minute)) {
+		t.Error("expected evicted signature to be unseen")
+	}
+
+	// Expired signatures are forgotten.
+	now = now.Add(2 * time.Minute)
+	if cache.Seen("key-1", "", []byte{1}, now.Add(time.Minute)) {
+		t.Error("expected expired signature to be unseen")
+	}
+	if !cache.Seen("key-1", "", []byte{3}, time.Time{}) {
+		t.Error("expected signature without expiry to stay seen")
+	}
+
+	// A reused nonce is a replay even with a different signature.
+	if cache.Seen("key-1", "nonce-1", []byte{4}, now.Add(time.Minute)) {
+		t.Error("expected first nonce use to be unseen")
+	}
+	if !cache.Seen("key-1", "nonce-1", []byte{5}, now.Add(time.Minute)) {
+		t.Error("expected reused nonce to be seen")
+	}
+}
+
+func TestLRUReplayCache_PruneIsBounded(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cache := NewLRUReplayCache(pruneStep * 2)
+	cache.now = func() time.Time { return now }
+
+	// The oldest half never expires, the newest half expires.
+	for i := 0; i < pruneStep*2; i++ {
+		expiresAt := time.Time{}
+		if i >= pruneStep {
+			expiresAt = now.Add(time.Minute)
+		}
+		cache.Seen("key", "", []byte{byte(i)}, expiresAt)
+	}
+	now = now.Add(2 * time.Minute)
+
+	// Only the tail is checked, so the least recently recorded is evicted
+	// rather than scanning the whole cache for the expired entries.
+	cache.Seen("key", "", []byte{0xFF}, time.Time{})
+	if cache.Len() != pruneStep*2 {
+		t.Fatalf("expected %d entries, got %d", pruneStep*2, cache.Len())
+	}
+	if cache.Seen("key", "", []byte{0}, time.Time{}) {
+		t.Error("expected least recently recorded signature to be evicted")
+	}
+}
+
+func TestValidateSignature_Freshness(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ed25519 key: %v", err)
+	}
+
+	sign := func(opts SignOptions) *http.Request {
+		req, _ := http.NewRequest("GET", "https://example.com/resource", nil)
+		opts.Request, opts.PrivateKey, opts.KeyID = req, priv, "test-key"
+		headers, err := CreateSignatureHeaders(opts)
+		if err != nil {
+			t.Fatalf("failed to sign request: %v", err)
+		}
+		req.Header.Set("Signature", headers.SignatureHeader)
+		req.Header.Set("Signature-Input", headers.SignatureInput)
+		return req
+	}
+
+	plain := sign(SignOptions{})
+	expiring := sign(SignOptions{Expires: time.Minute, Nonce: "abc"})
+
+	tests := []struct {
+		name  string
+		req   *http.Request
+		after time.Duration
+		setup func(*ValidationOptions)
+		err   error
+	}{
+		{"fresh", plain, 0, func(o *ValidationOptions) { o.MaxAge = time.Minute }, nil},
+		{"too old", plain, 2 * time.Minute, func(o *ValidationOptions) { o.MaxAge = time.Minute }, ErrSignatureExpired},
+		{"old within skew", plain, 70 * time.Second, func(o *ValidationOptions) { o.MaxAge, o.ClockSkew = time.Minute, 30*time.Second }, nil},
+		{"created in the future", plain, -time.Minute, func(o *ValidationOptions) {}, ErrSignatureNotYetValid},
+		{"future within skew", plain, -time.Minute, func(o *ValidationOptions) { o.ClockSkew = 2 * time.Minute }, nil},
+		{"expires missing", plain, 0, func(o *ValidationOptions) { o.RequireExpires = true }, ErrInvalidSignature},
+		{"not expired", expiring, 30 * time.Second, func(o *ValidationOptions) { o.RequireExpires = true }, nil},
+		{"expired", expiring, 2 * time.Minute, func(o *ValidationOptions) {}, ErrSignatureExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := NewValidationOptions(tt.req, tt.req.Header, pub)
+			opts.Now = func() time.Time { return time.Now().Add(tt.after) }
+			tt.setup(opts)
+			if err := ValidateSignature(opts); !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestValidateSignature_Replay(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ed25519 key: %v", err)
+	}
+
+	req, _ := http.NewRequest("GET", "https://example.com/resource", nil)
+	headers, err := CreateSignatureHeaders(SignOptions{Request: req, PrivateKey: priv, KeyID: "test-key"})
+	if err != nil {
+		t.Fatalf("failed to sign request: %v", err)
+	}
+	req.Header.Set("Signature", headers.SignatureHeader)
+	req.Header.Set("Signature-Input", headers.SignatureInput)
+
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	opts := NewValidationOptions(req, req.Header, other)
+	opts.MaxAge = time.Minute
+	opts.ReplayCache = NewLRUReplayCache(0)
+
+	// A signature that fails verification is not recorded.
+	if err := ValidateSignature(opts); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+
+	opts.PublicKey = pub
+	if err := ValidateSignature(opts); err != nil {
+		t.Fatalf("expected first use to validate: %v", err)
+	}
+	if err := ValidateSignature(opts); !errors.Is(err, ErrSignatureReplayed) {
+		t.Errorf("expected ErrSignatureReplayed, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package httpsignatureutils
+
+import (
+	"crypto/ed25519"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ResponseSignOptions are the options for signing an HTTP response.
+type ResponseSignOptions struct {
+	Response   *http.Response
+	Request    *http.Request // The request the response answers. Defaults to Response.Request.
+	PrivateKey ed25519.PrivateKey
+	KeyID      string
+	Signer     Signer // Used instead of PrivateKey and KeyID when set.
+	Label      string // Defaults to DefaultSignatureLabel.
+	Algorithm  string // Defaults to the algorithm for the signer's key type.
+
+	// Components are the covered components. Components with the req
+	// parameter are taken from Request. Defaults to
+	// DefaultResponseComponents.
+	Components []ComponentID
+
+	Expires time.Duration // Adds an expires parameter this long after created when non-zero.
+	Nonce   string        // Adds a nonce parameter when set.
+}
+
+// DefaultResponseComponents covers the status, the body's digest and type if
+// the response has them, and binds the response to its request through the
+// request's method, target URI and first signature.
+func DefaultResponseComponents(resp *http.Response, req *http.Request) []ComponentID {
+	components := []ComponentID{{Name: "@status"}}
+	for _, name := range []string{"content-digest", "content-type"} {
+		if resp.Header.Get(name) != "" {
+			components = append(components, ComponentID{Name: name})
+		}
+	}
+	if req == nil {
+		return components
+	}
+
+	bound := Params{{Key: "req", Value: true}}
+	components = append(components,
+		ComponentID{Name: "@method", Params: bound},
+		ComponentID{Name: "@target-uri", Params: bound},
+	)
+	sigs, err := ParseSignatures(
+		strings.Join(req.Header.Values("Signature-Input"), ", "),
+		strings.Join(req.Header.Values("Signature"), ", "),
+	)
+	if err == nil {
+		components = append(components, ComponentID{
+			Name:   "signature",
+			Params: Params{{Key: "req", Value: true}, {Key: "key", Value: sigs[0].Label}},
+		})
+	}
+	return components
+}
+
+// CreateResponseSignatureHeaders signs a response, e.g. in a server handler
+// before the headers are written.
+func CreateResponseSignatureHeaders(opts ResponseSignOptions) (*SignatureHeaders, error) {
+	req := opts.Request
+	if req == nil {
+		req = opts.Response.Request
+	}
+	components := opts.Components
+	if components == nil {
+		components = DefaultResponseComponents(opts.Response, req)
+	}
+	return signMessage(message{req: req, resp: opts.Response}, signParams{
+		signer:     opts.Signer,
+		privateKey: opts.PrivateKey,
+		keyID:      opts.KeyID,
+		label:      opts.Label,
+		algorithm:  opts.Algorithm,
+		components: components,
+		expires:    opts.Expires,
+		nonce:      opts.Nonce,
+	})
+}
@@ -0,0 +1,80 @@
+package httpsignatureutils
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestResponseSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ed25519 key: %v", err)
+	}
+
+	newExchange := func() (*http.Request, *http.Response) {
+		req, _ := http.NewRequest("GET", "https://example.com/incoming-payments/1", nil)
+		reqHeaders, err := CreateSignatureHeaders(SignOptions{Request: req, PrivateKey: priv, KeyID: "client-key"})
+		if err != nil {
+			t.Fatalf("failed to sign request: %v", err)
+		}
+		req.Header.Set("Signature", reqHeaders.SignatureHeader)
+		req.Header.Set("Signature-Input", reqHeaders.SignatureInput)
+
+		body := []byte(`{"id":"1"}`)
+		content := CreateContentHeaders(body)
+		resp := &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       io.NopCloser(bytes.NewReader(body)),
+			Request:    req,
+		}
+		resp.Header.Set("Content-Digest", content.ContentDigest)
+		resp.Header.Set("Content-Type", content.ContentType)
+
+		headers, err := CreateResponseSignatureHeaders(ResponseSignOptions{Response: resp, PrivateKey: priv, KeyID: "server-key"})
+		if err != nil {
+			t.Fatalf("failed to sign response: %v", err)
+		}
+		resp.Header.Set("Signature", headers.SignatureHeader)
+		resp.Header.Set("Signature-Input", headers.SignatureInput)
+		return req, resp
+	}
+
+	_, resp := newExchange()
+	expected := `sig1=("@status" "content-digest" "content-type" "@method";req "@target-uri";req "signature";req;key="sig1")`
+	if !strings.HasPrefix(resp.Header.Get("Signature-Input"), expected) {
+		t.Errorf("unexpected Signature-Input: %s", resp.Header.Get("Signature-Input"))
+	}
+	if err := ValidateSignature(NewResponseValidationOptions(resp, pub)); err != nil {
+		t.Fatalf("expected response signature to validate: %v", err)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != `{"id":"1"}` {
+		t.Errorf("body was not restored, got %q", body)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(*http.Request, *http.Response)
+		err    error
+	}{
+		{"status", func(_ *http.Request, resp *http.Response) { resp.StatusCode = http.StatusCreated }, ErrInvalidSignature},
+		{"body", func(_ *http.Request, resp *http.Response) { resp.Body = io.NopCloser(strings.NewReader(`{"id":"2"}`)) }, ErrContentDigestMismatch},
+		{"request", func(req *http.Request, _ *http.Response) { req.URL.Path = "/incoming-payments/2" }, ErrInvalidSignature},
+		{"request signature", func(req *http.Request, _ *http.Response) { req.Header.Set("Signature", "sig1=:AAAA:") }, ErrInvalidSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, resp := newExchange()
+			tt.tamper(req, resp)
+			if err := ValidateSignature(NewResponseValidationOptions(resp, pub)); !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
@@ -22,15 +22,34 @@ type ContentHeaders struct {
 	ContentType   string
 }
 
+// SignatureHeaders are the values of the Signature and Signature-Input
+// headers, e.g. sig1=:<base64>: and sig1=("@method" ...);created=...
 type SignatureHeaders struct {
-	Signature      string
-	SignatureInput string
+	Signature       string // The bare base64 signature, without the label.
+	SignatureHeader string // The Signature header value, e.g. sig1=:<base64>:.
+	SignatureInput  string
+	SignatureBase   string // The signed base, for debugging.
 }
 
+// DefaultSignatureLabel is the label signatures are created with unless
+// SignOptions.Label is set.
+const DefaultSignatureLabel = "sig1"
+
 type SignOptions struct {
 	Request    *http.Request
 	PrivateKey ed25519.PrivateKey
 	KeyID      string
+	Signer     Signer // Used instead of PrivateKey and KeyID when set.
+	Label      string // Defaults to DefaultSignatureLabel.
+	Algorithm  string // Defaults to the algorithm for the signer's key type.
+
+	// Components are the covered components, e.g. {Name: "@authority"} or
+	// {Name: "@query-param", Params: Params{{Key: "name", Value: "id"}}}.
+	// Defaults to the components Open Payments requires for the request.
+	Components []ComponentID
+
+	Expires time.Duration // Adds an expires parameter this long after created when non-zero.
+	Nonce   string        // Adds a nonce parameter when set.
 }
 
 func createContentDigest(body []byte) string {
@@ -48,80 +67,129 @@ func CreateContentHeaders(body []byte) ContentHeaders {
 	}
 }
 
-func createSignatureBaseString(req *http.Request, components []string, created int64, keyID string) (string, error) {
-	var parts []string
+func defaultSignatureParams(components []string, created int64, keyID string) SignatureParams {
+	params := NewSignatureParams(components...)
+	params.Params.Set("created", created)
+	params.Params.Set("keyid", keyID)
+	params.Params.Set("alg", "ed25519")
+	return params
+}
+
+// signatureBase builds the signature base (RFC 9421, section 2.5) for the
+// covered components and parameters in params.
+func signatureBase(req *http.Request, params SignatureParams) (string, error) {
+	return messageSignatureBase(message{req: req}, params)
+}
 
-	for _, comp := range components {
-		var value string
-		switch strings.ToLower(comp) {
-		case "@method":
-			value = req.Method
-		case "@target-uri":
-			urlStr := req.URL.String()
-			// Add a trailing slash only if there's no path component
-			if req.URL.Path == "" {
-				urlStr += "/"
-			}
-			value = urlStr
-		case "authorization":
-			value = req.Header.Get("Authorization")
-		case "content-digest":
-			value = req.Header.Get("Content-Digest")
-		case "content-length":
-			value = req.Header.Get("Content-Length")
-		case "content-type":
-			value = req.Header.Get("Content-Type")
-		default:
-			// try to get any other component as a header.
-			value = req.Header.Get(comp)
-			if value == "" {
-				return "", fmt.Errorf("%w: %s", ErrMissingRequiredHeader, comp)
-			}
+func messageSignatureBase(msg message, params SignatureParams) (string, error) {
+	var parts []string
+	for _, component := range params.Components {
+		values, err := componentValues(msg, component)
+		if err != nil {
+			return "", err
+		}
+		for _, value := range values {
+			parts = append(parts, fmt.Sprintf("%s: %s", component, value))
 		}
-		parts = append(parts, fmt.Sprintf("\"%s\": %s", comp, value))
 	}
 
-	quotedComponents := make([]string, len(components))
-	for i, comp := range components {
-		quotedComponents[i] = fmt.Sprintf("\"%s\"", comp)
+	sigParams, err := params.Serialize()
+	if err != nil {
+		return "", err
 	}
-	sigParams := fmt.Sprintf("(%s);created=%d;keyid=\"%s\";alg=\"ed25519\"", strings.Join(quotedComponents, " "), created, keyID)
-
 	parts = append(parts, fmt.Sprintf("\"@signature-params\": %s", sigParams))
 
 	return strings.Join(parts, "\n"), nil
 }
 
-func CreateSignatureHeaders(opts SignOptions) (*SignatureHeaders, error) {
+// defaultComponents are the components Open Payments requires to be signed.
+func defaultComponents(req *http.Request) []string {
 	components := []string{"@method", "@target-uri"}
 
-	if opts.Request.Header.Get("Authorization") != "" || opts.Request.Header.Get("authorization") != "" {
+	if req.Header.Get("Authorization") != "" {
 		components = append(components, "authorization")
 	}
 
-	if opts.Request.ContentLength > 0 {
+	if req.ContentLength > 0 {
 		components = append(components, "content-digest", "content-length", "content-type")
 	}
+	return components
+}
 
-	created := time.Now().Unix()
+func CreateSignatureHeaders(opts SignOptions) (*SignatureHeaders, error) {
+	components := opts.Components
+	if components == nil {
+		components = NewSignatureParams(defaultComponents(opts.Request)...).Components
+	}
+	return signMessage(message{req: opts.Request}, signParams{
+		signer:     opts.Signer,
+		privateKey: opts.PrivateKey,
+		keyID:      opts.KeyID,
+		label:      opts.Label,
+		algorithm:  opts.Algorithm,
+		components: components,
+		expires:    opts.Expires,
+		nonce:      opts.Nonce,
+	})
+}
+
+// signParams are the options shared by request and response signing.
+type signParams struct {
+	signer     Signer
+	privateKey ed25519.PrivateKey
+	keyID      string
+	label      string
+	algorithm  string
+	components []ComponentID
+	expires    time.Duration
+	nonce      string
+}
 
-	signatureBase, err := createSignatureBaseString(opts.Request, components, created, opts.KeyID)
+func signMessage(msg message, sp signParams) (*SignatureHeaders, error) {
+	signer := sp.signer
+	if signer == nil {
+		signer = NewEd25519Signer(sp.privateKey, sp.keyID)
+	}
+	label := sp.label
+	if label == "" {
+		label = DefaultSignatureLabel
+	}
 
+	alg, err := resolveAlgorithm(sp.algorithm, signer.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	created := time.Now().Unix()
+	params := defaultSignatureParams(nil, created, signer.KeyID())
+	params.Params.Set("alg", alg.Name())
+	params.Components = sp.components
+	if sp.expires != 0 {
+		params.Params.Set("expires", created+int64(sp.expires/time.Second))
+	}
+	if sp.nonce != "" {
+		params.Params.Set("nonce", sp.nonce)
+	}
+
+	signatureBase, err := messageSignatureBase(msg, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create signature base string: %w", err)
 	}
 
-	signatureBytes := ed25519.Sign(opts.PrivateKey, []byte(signatureBase))
-	signature := base64.StdEncoding.EncodeToString(signatureBytes)
+	signatureBytes, err := signBase(alg, signer, signatureBase)
+	if err != nil {
+		return nil, err
+	}
 
-	quotedComponents := make([]string, len(components))
-	for i, comp := range components {
-		quotedComponents[i] = fmt.Sprintf("\"%s\"", comp)
+	signatureInput, signature, err := FormatSignatures(MessageSignature{Label: label, Params: params, Signature: signatureBytes})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize signature headers: %w", err)
 	}
-	signatureInput := fmt.Sprintf("sig1=(%s);created=%d;keyid=\"%s\";alg=\"ed25519\"", strings.Join(quotedComponents, " "), created, opts.KeyID)
 
 	return &SignatureHeaders{
-		Signature:      signature,
-		SignatureInput: signatureInput,
+		Signature:       base64.StdEncoding.EncodeToString(signatureBytes),
+		SignatureHeader: signature,
+		SignatureInput:  signatureInput,
+		SignatureBase:   signatureBase,
 	}, nil
 }
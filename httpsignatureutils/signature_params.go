@@ -0,0 +1,159 @@
+package httpsignatureutils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ComponentID identifies a covered component of a signature, e.g.
+// "content-digest" or "@method".
+type ComponentID struct {
+	Name   string
+	Params Params
+}
+
+func (c ComponentID) item() Item {
+	return Item{Value: c.Name, Params: c.Params}
+}
+
+// String returns the serialized component identifier as used in the
+// signature base, e.g. "content-digest".
+func (c ComponentID) String() string {
+	s, err := c.item().Serialize()
+	if err != nil {
+		return fmt.Sprintf("%q", c.Name)
+	}
+	return s
+}
+
+// SignatureParams are the covered components and parameters of a single
+// signature, as carried in the Signature-Input header. Params keep the order
+// they were parsed or set in so they serialize back exactly.
+type SignatureParams struct {
+	Components []ComponentID
+	Params     Params
+}
+
+// NewSignatureParams returns signature params covering the named components.
+func NewSignatureParams(components ...string) SignatureParams {
+	sp := SignatureParams{Components: make([]ComponentID, len(components))}
+	for i, name := range components {
+		sp.Components[i] = ComponentID{Name: name}
+	}
+	return sp
+}
+
+func (sp SignatureParams) intParam(key string) (int64, bool) {
+	v, ok := sp.Params.Get(key)
+	i, isInt := v.(int64)
+	return i, ok && isInt
+}
+
+func (sp SignatureParams) stringParam(key string) (string, bool) {
+	v, ok := sp.Params.Get(key)
+	s, isString := v.(string)
+	return s, ok && isString
+}
+
+func (sp SignatureParams) Created() (int64, bool) { return sp.intParam("created") }
+func (sp SignatureParams) Expires() (int64, bool) { return sp.intParam("expires") }
+func (sp SignatureParams) Nonce() (string, bool)  { return sp.stringParam("nonce") }
+func (sp SignatureParams) Alg() (string, bool)    { return sp.stringParam("alg") }
+func (sp SignatureParams) KeyID() (string, bool)  { return sp.stringParam("keyid") }
+func (sp SignatureParams) Tag() (string, bool)    { return sp.stringParam("tag") }
+
+func (sp SignatureParams) innerList() InnerList {
+	items := make([]Item, len(sp.Components))
+	for i, c := range sp.Components {
+		items[i] = c.item()
+	}
+	return InnerList{Items: items, Params: sp.Params}
+}
+
+// Serialize returns the inner list used as the Signature-Input member and as
+// the value of @signature-params in the signature base.
+func (sp SignatureParams) Serialize() (string, error) {
+	return sp.innerList().Serialize()
+}
+
+func signatureParamsFromInnerList(il InnerList) (SignatureParams, error) {
+	sp := SignatureParams{Params: il.Params}
+	for _, item := range il.Items {
+		name, ok := item.Value.(string)
+		if !ok {
+			return SignatureParams{}, fmt.Errorf("%w: component identifier must be a string", ErrInvalidSignature)
+		}
+		sp.Components = append(sp.Components, ComponentID{Name: name, Params: item.Params})
+	}
+	return sp, nil
+}
+
+// MessageSignature is one labeled signature of a message.
+type MessageSignature struct {
+	Label     string
+	Params    SignatureParams
+	Signature []byte
+}
+
+// ParseSignatures parses the Signature-Input and Signature header values into
+// their labeled signatures, in Signature-Input order. Multiple header lines
+// should be joined with ", ".
+func ParseSignatures(signatureInput string, signature string) ([]MessageSignature, error) {
+	if strings.TrimSpace(signatureInput) == "" {
+		return nil, ErrMissingSignatureInput
+	}
+	if strings.TrimSpace(signature) == "" {
+		return nil, ErrMissingSignature
+	}
+
+	inputs, err := ParseDictionary(signatureInput)
+	if err != nil {
+		return nil, fmt.Errorf("%w: Signature-Input: %w", ErrInvalidSignature, err)
+	}
+	values, err := ParseDictionary(signature)
+	if err != nil {
+		return nil, fmt.Errorf("%w: Signature: %w", ErrInvalidSignature, err)
+	}
+
+	sigs := make([]MessageSignature, 0, len(inputs))
+	for _, member := range inputs {
+		il, ok := member.Value.(InnerList)
+		if !ok {
+			return nil, fmt.Errorf("%w: Signature-Input %q is not an inner list", ErrInvalidSignature, member.Key)
+		}
+		params, err := signatureParamsFromInnerList(il)
+		if err != nil {
+			return nil, err
+		}
+
+		value, ok := values.Get(member.Key)
+		if !ok {
+			return nil, fmt.Errorf("%w: no signature for label %q", ErrMissingSignature, member.Key)
+		}
+		item, ok := value.(Item)
+		sigBytes, isBytes := item.Value.([]byte)
+		if !ok || !isBytes {
+			return nil, fmt.Errorf("%w: signature %q is not a byte sequence", ErrInvalidSignature, member.Key)
+		}
+
+		sigs = append(sigs, MessageSignature{Label: member.Key, Params: params, Signature: sigBytes})
+	}
+	return sigs, nil
+}
+
+// FormatSignatures serializes signatures into Signature-Input and Signature
+// header values.
+func FormatSignatures(sigs ...MessageSignature) (signatureInput string, signature string, err error) {
+	var inputs, values Dictionary
+	for _, sig := range sigs {
+		inputs.Set(sig.Label, sig.Params.innerList())
+		values.Set(sig.Label, Item{Value: sig.Signature})
+	}
+	if signatureInput, err = inputs.Serialize(); err != nil {
+		return "", "", err
+	}
+	if signature, err = values.Serialize(); err != nil {
+		return "", "", err
+	}
+	return signatureInput, signature, nil
+}
@@ -3,7 +3,6 @@ package httpsignatureutils
 import (
 	"crypto/ed25519"
 	"crypto/rand"
-	"encoding/base64"
 	"net/http"
 	"strings"
 	"testing"
@@ -33,6 +32,9 @@ func TestCreateSignatureHeaders_Basic(t *testing.T) {
 	if headers.Signature == "" {
 		t.Error("Expected non-empty Signature")
 	}
+	if headers.SignatureHeader != "sig1=:"+headers.Signature+":" {
+		t.Errorf("unexpected Signature header %q for signature %q", headers.SignatureHeader, headers.Signature)
+	}
 	if headers.SignatureInput == "" {
 		t.Error("Expected non-empty SignatureInput")
 	}
@@ -54,10 +56,11 @@ func TestCreateSignatureHeaders_Basic(t *testing.T) {
 		t.Fatalf("Failed to create signature base string: %v", err)
 	}
 
-	sigBytes, err := base64.StdEncoding.DecodeString(headers.Signature)
+	sigs, err := ParseSignatures(headers.SignatureInput, headers.SignatureHeader)
 	if err != nil {
-		t.Fatalf("Failed to decode signature: %v", err)
+		t.Fatalf("Failed to parse signature: %v", err)
 	}
+	sigBytes := sigs[0].Signature
 	if !ed25519.Verify(pub, []byte(baseString), sigBytes) {
 		t.Error("Signature verification failed")
 	}
@@ -119,3 +122,9 @@ func TestCreateSignatureHeaders_WithBody(t *testing.T) {
 		t.Error("SignatureInput should contain 'content-length' when body is present")
 	}
 }
+
+// createSignatureBaseString builds the signature base for components with the
+// created, keyid and alg parameters written by CreateSignatureHeaders.
+func createSignatureBaseString(req *http.Request, components []string, created int64, keyID string) (string, error) {
+	return signatureBase(req, defaultSignatureParams(components, created, keyID))
+}
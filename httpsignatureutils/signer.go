@@ -0,0 +1,98 @@
+package httpsignatureutils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"fmt"
+	"io"
+)
+
+// Signer signs HTTP message signature bases. It lets the private key live
+// outside process memory, e.g. in an HSM or KMS.
+//
+// For Ed25519 and HMAC keys, Sign is called with the full signature base as
+// the message and crypto.Hash(0) as opts, as for ed25519.PrivateKey. For ECDSA
+// and RSA keys it is called with the digest of the signature base, as
+// crypto.Signer documents.
+type Signer interface {
+	crypto.Signer
+	KeyID() string
+}
+
+type ed25519Signer struct {
+	key   ed25519.PrivateKey
+	keyID string
+}
+
+// NewEd25519Signer returns a Signer for an in-memory Ed25519 private key.
+func NewEd25519Signer(key ed25519.PrivateKey, keyID string) Signer {
+	return &ed25519Signer{key: key, keyID: keyID}
+}
+
+func (s *ed25519Signer) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+func (s *ed25519Signer) Sign(rand io.Reader, message []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.key.Sign(rand, message, opts)
+}
+
+func (s *ed25519Signer) KeyID() string {
+	return s.keyID
+}
+
+type keySigner struct {
+	crypto.Signer
+	keyID string
+}
+
+// NewSigner returns a Signer for an in-memory private key, e.g. an
+// *ecdsa.PrivateKey or *rsa.PrivateKey.
+func NewSigner(key crypto.Signer, keyID string) Signer {
+	return &keySigner{Signer: key, keyID: keyID}
+}
+
+func (s *keySigner) KeyID() string {
+	return s.keyID
+}
+
+type hmacSigner struct {
+	secret HMACKey
+	keyID  string
+}
+
+// NewHMACSigner returns a Signer creating hmac-sha256 signatures with secret.
+func NewHMACSigner(secret []byte, keyID string) Signer {
+	return &hmacSigner{secret: HMACKey(secret), keyID: keyID}
+}
+
+// Public returns the shared secret, which is also the verification key.
+func (s *hmacSigner) Public() crypto.PublicKey {
+	return s.secret
+}
+
+func (s *hmacSigner) Sign(_ io.Reader, message []byte, _ crypto.SignerOpts) ([]byte, error) {
+	return hmacSHA256(s.secret, message), nil
+}
+
+func (s *hmacSigner) KeyID() string {
+	return s.keyID
+}
+
+// SignatureBaseObserver is implemented by Signers that want to see each
+// signature base before it is signed, as ECDSA and RSA signers are only passed
+// its digest. signertest.RecordingSigner uses it.
+type SignatureBaseObserver interface {
+	ObserveSignatureBase(signatureBase string)
+}
+
+func signBase(alg Algorithm, signer Signer, signatureBase string) ([]byte, error) {
+	if observer, ok := signer.(SignatureBaseObserver); ok {
+		observer.ObserveSignatureBase(signatureBase)
+	}
+	signature, err := alg.Sign(signer, []byte(signatureBase))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+	return signature, nil
+}
@@ -0,0 +1,67 @@
+// Package signertest provides a Signer test double for code that creates
+// HTTP message signatures.
+package signertest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"sync"
+
+	"github.com/interledger/open-payments-go/httpsignatureutils"
+)
+
+// RecordingSigner wraps a Signer and records every signature base it is
+// asked to sign, whatever the key type. Bases are recorded when signing through
+// httpsignatureutils, not by calling Sign directly.
+type RecordingSigner struct {
+	httpsignatureutils.Signer
+
+	mu    sync.Mutex
+	bases []string
+}
+
+// New returns a RecordingSigner backed by a freshly generated Ed25519 key.
+func New(keyID string) *RecordingSigner {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		panic("signertest: failed to generate key: " + err.Error())
+	}
+	return Wrap(httpsignatureutils.NewEd25519Signer(key, keyID))
+}
+
+// Wrap returns a RecordingSigner that delegates signing to signer.
+func Wrap(signer httpsignatureutils.Signer) *RecordingSigner {
+	return &RecordingSigner{Signer: signer}
+}
+
+// ObserveSignatureBase records signatureBase. It implements
+// httpsignatureutils.SignatureBaseObserver.
+func (s *RecordingSigner) ObserveSignatureBase(signatureBase string) {
+	s.mu.Lock()
+	s.bases = append(s.bases, signatureBase)
+	s.mu.Unlock()
+}
+
+// Bases returns the signature bases signed so far, oldest first.
+func (s *RecordingSigner) Bases() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bases...)
+}
+
+// LastBase returns the most recently signed signature base, or "" if none.
+func (s *RecordingSigner) LastBase() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.bases) == 0 {
+		return ""
+	}
+	return s.bases[len(s.bases)-1]
+}
+
+// PublicKey returns the Ed25519 public key of the wrapped signer, or nil if it
+// holds another key type.
+func (s *RecordingSigner) PublicKey() ed25519.PublicKey {
+	key, _ := s.Public().(ed25519.PublicKey)
+	return key
+}
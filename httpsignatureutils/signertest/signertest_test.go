@@ -0,0 +1,65 @@
+package signertest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/interledger/open-payments-go/httpsignatureutils"
+)
+
+func TestRecordingSigner(t *testing.T) {
+	signer := New("test-key")
+
+	req, _ := http.NewRequest("GET", "https://example.com/resource", nil)
+	req.Header.Set("Authorization", "GNAP token")
+
+	headers, err := httpsignatureutils.CreateSignatureHeaders(httpsignatureutils.SignOptions{Request: req, Signer: signer})
+	if err != nil {
+		t.Fatalf("CreateSignatureHeaders returned error: %v", err)
+	}
+
+	bases := signer.Bases()
+	if len(bases) != 1 {
+		t.Fatalf("expected 1 recorded base, got %d", len(bases))
+	}
+	expectedPrefix := "\"@method\": GET\n\"@target-uri\": https://example.com/resource\n\"authorization\": GNAP token\n\"@signature-params\": "
+	if !strings.HasPrefix(signer.LastBase(), expectedPrefix) {
+		t.Errorf("unexpected signature base:\n%s", signer.LastBase())
+	}
+	if !strings.Contains(headers.SignatureInput, `keyid="test-key"`) {
+		t.Errorf("SignatureInput missing keyid: %s", headers.SignatureInput)
+	}
+
+	req.Header.Set("Signature", headers.SignatureHeader)
+	req.Header.Set("Signature-Input", headers.SignatureInput)
+	if err := httpsignatureutils.ValidateSignature(httpsignatureutils.NewValidationOptions(req, req.Header, signer.PublicKey())); err != nil {
+		t.Fatalf("expected signature to validate: %v", err)
+	}
+}
+
+func TestRecordingSigner_ECDSA(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate ECDSA key: %v", err)
+	}
+	signer := Wrap(httpsignatureutils.NewSigner(key, "test-key"))
+
+	req, _ := http.NewRequest("GET", "https://example.com/resource", nil)
+	headers, err := httpsignatureutils.CreateSignatureHeaders(httpsignatureutils.SignOptions{Request: req, Signer: signer})
+	if err != nil {
+		t.Fatalf("CreateSignatureHeaders returned error: %v", err)
+	}
+	if headers.SignatureBase == "" || signer.LastBase() != headers.SignatureBase {
+		t.Errorf("expected the signature base to be recorded, got %q", signer.LastBase())
+	}
+
+	req.Header.Set("Signature", headers.SignatureHeader)
+	req.Header.Set("Signature-Input", headers.SignatureInput)
+	if err := httpsignatureutils.ValidateSignature(httpsignatureutils.NewValidationOptions(req, req.Header, &key.PublicKey)); err != nil {
+		t.Fatalf("expected signature to validate: %v", err)
+	}
+}
@@ -0,0 +1,650 @@
+package httpsignatureutils
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Structured field values as defined by RFC 8941, used by the
+// Signature-Input and Signature headers.
+//
+// Bare item values are represented as int64 (Integer), float64 (Decimal),
+// string (String), Token, []byte (Byte Sequence) and bool (Boolean).
+
+var (
+	ErrInvalidStructuredField = errors.New("invalid structured field")
+)
+
+// Token is a structured field token, which is serialized without quotes.
+type Token string
+
+type Param struct {
+	Key   string
+	Value any
+}
+
+// Params are the ordered parameters of an item or inner list.
+type Params []Param
+
+func (p Params) Get(key string) (any, bool) {
+	for _, param := range p {
+		if param.Key == key {
+			return param.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set replaces the value of key in place, or appends it.
+func (p *Params) Set(key string, value any) {
+	for i := range *p {
+		if (*p)[i].Key == key {
+			(*p)[i].Value = value
+			return
+		}
+	}
+	*p = append(*p, Param{Key: key, Value: value})
+}
+
+func (p *Params) Delete(key string) {
+	for i := range *p {
+		if (*p)[i].Key == key {
+			*p = append((*p)[:i], (*p)[i+1:]...)
+			return
+		}
+	}
+}
+
+type Item struct {
+	Value  any
+	Params Params
+}
+
+type InnerList struct {
+	Items  []Item
+	Params Params
+}
+
+type DictMember struct {
+	Key   string
+	Value any // Item or InnerList
+}
+
+// Dictionary is an ordered structured field dictionary.
+type Dictionary []DictMember
+
+func (d Dictionary) Get(key string) (any, bool) {
+	for _, member := range d {
+		if member.Key == key {
+			return member.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Set replaces the value of key in place, or appends it.
+func (d *Dictionary) Set(key string, value any) {
+	for i := range *d {
+		if (*d)[i].Key == key {
+			(*d)[i].Value = value
+			return
+		}
+	}
+	*d = append(*d, DictMember{Key: key, Value: value})
+}
+
+// List is a structured field list of Item and InnerList members.
+type List []any
+
+func ParseDictionary(s string) (Dictionary, error) {
+	p := &sfParser{s: s}
+	p.skipSP()
+	dict, err := p.parseDictionary()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSP()
+	if !p.eof() {
+		return nil, p.errorf("unexpected trailing characters")
+	}
+	return dict, nil
+}
+
+func ParseList(s string) (List, error) {
+	p := &sfParser{s: s}
+	p.skipSP()
+	list, err := p.parseList()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSP()
+	if !p.eof() {
+		return nil, p.errorf("unexpected trailing characters")
+	}
+	return list, nil
+}
+
+func ParseItem(s string) (Item, error) {
+	p := &sfParser{s: s}
+	p.skipSP()
+	item, err := p.parseItem()
+	if err != nil {
+		return Item{}, err
+	}
+	p.skipSP()
+	if !p.eof() {
+		return Item{}, p.errorf("unexpected trailing characters")
+	}
+	return item, nil
+}
+
+type sfParser struct {
+	s   string
+	pos int
+}
+
+func (p *sfParser) eof() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *sfParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *sfParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w: %s at offset %d", ErrInvalidStructuredField, fmt.Sprintf(format, args...), p.pos)
+}
+
+func (p *sfParser) skipSP() {
+	for !p.eof() && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+func (p *sfParser) skipOWS() {
+	for !p.eof() && (p.s[p.pos] == ' ' || p.s[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// afterMember consumes the separator between list or dictionary members and
+// reports whether another member follows.
+func (p *sfParser) afterMember() (bool, error) {
+	p.skipOWS()
+	if p.eof() {
+		return false, nil
+	}
+	if p.peek() != ',' {
+		return false, p.errorf("expected ','")
+	}
+	p.pos++
+	p.skipOWS()
+	if p.eof() {
+		return false, p.errorf("trailing ','")
+	}
+	return true, nil
+}
+
+func (p *sfParser) parseDictionary() (Dictionary, error) {
+	var dict Dictionary
+	for !p.eof() {
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+
+		var member any
+		if p.peek() == '=' {
+			p.pos++
+			member, err = p.parseItemOrInnerList()
+		} else {
+			var params Params
+			params, err = p.parseParams()
+			member = Item{Value: true, Params: params}
+		}
+		if err != nil {
+			return nil, err
+		}
+		dict.Set(key, member)
+
+		more, err := p.afterMember()
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			break
+		}
+	}
+	return dict, nil
+}
+
+func (p *sfParser) parseList() (List, error) {
+	var list List
+	for !p.eof() {
+		member, err := p.parseItemOrInnerList()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, member)
+
+		more, err := p.afterMember()
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			break
+		}
+	}
+	return list, nil
+}
+
+func (p *sfParser) parseItemOrInnerList() (any, error) {
+	if p.peek() == '(' {
+		return p.parseInnerList()
+	}
+	return p.parseItem()
+}
+
+func (p *sfParser) parseInnerList() (InnerList, error) {
+	if p.peek() != '(' {
+		return InnerList{}, p.errorf("expected '('")
+	}
+	p.pos++
+
+	var list InnerList
+	for !p.eof() {
+		p.skipSP()
+		if p.peek() == ')' {
+			p.pos++
+			params, err := p.parseParams()
+			if err != nil {
+				return InnerList{}, err
+			}
+			list.Params = params
+			return list, nil
+		}
+
+		item, err := p.parseItem()
+		if err != nil {
+			return InnerList{}, err
+		}
+		list.Items = append(list.Items, item)
+
+		if c := p.peek(); c != ' ' && c != ')' {
+			return InnerList{}, p.errorf("expected ' ' or ')' in inner list")
+		}
+	}
+	return InnerList{}, p.errorf("unterminated inner list")
+}
+
+func (p *sfParser) parseItem() (Item, error) {
+	value, err := p.parseBareItem()
+	if err != nil {
+		return Item{}, err
+	}
+	params, err := p.parseParams()
+	if err != nil {
+		return Item{}, err
+	}
+	return Item{Value: value, Params: params}, nil
+}
+
+func (p *sfParser) parseParams() (Params, error) {
+	var params Params
+	for p.peek() == ';' {
+		p.pos++
+		p.skipSP()
+		key, err := p.parseKey()
+		if err != nil {
+			return nil, err
+		}
+		var value any = true
+		if p.peek() == '=' {
+			p.pos++
+			value, err = p.parseBareItem()
+			if err != nil {
+				return nil, err
+			}
+		}
+		params.Set(key, value)
+	}
+	return params, nil
+}
+
+func isLCAlpha(c byte) bool {
+	return c >= 'a' && c <= 'z'
+}
+
+func isAlpha(c byte) bool {
+	return isLCAlpha(c) || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isTChar(c byte) bool {
+	return isAlpha(c) || isDigit(c) || strings.IndexByte("!#$%&'*+-.^_`|~", c) >= 0
+}
+
+func isKeyChar(c byte) bool {
+	return isLCAlpha(c) || isDigit(c) || c == '_' || c == '-' || c == '.' || c == '*'
+}
+
+func (p *sfParser) parseKey() (string, error) {
+	if c := p.peek(); !isLCAlpha(c) && c != '*' {
+		return "", p.errorf("invalid key")
+	}
+	start := p.pos
+	for !p.eof() && isKeyChar(p.s[p.pos]) {
+		p.pos++
+	}
+	return p.s[start:p.pos], nil
+}
+
+func (p *sfParser) parseBareItem() (any, error) {
+	c := p.peek()
+	switch {
+	case c == '-' || isDigit(c):
+		return p.parseNumber()
+	case c == '"':
+		return p.parseString()
+	case c == '*' || isAlpha(c):
+		return p.parseToken(), nil
+	case c == ':':
+		return p.parseByteSequence()
+	case c == '?':
+		return p.parseBoolean()
+	}
+	return nil, p.errorf("invalid bare item")
+}
+
+func (p *sfParser) parseNumber() (any, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	if !isDigit(p.peek()) {
+		return nil, p.errorf("invalid number")
+	}
+
+	digits, decimal, fracDigits := 0, false, 0
+	for !p.eof() {
+		c := p.s[p.pos]
+		if isDigit(c) {
+			digits++
+			if decimal {
+				fracDigits++
+			}
+		} else if c == '.' && !decimal {
+			if digits > 12 {
+				return nil, p.errorf("decimal has too many integer digits")
+			}
+			decimal = true
+		} else {
+			break
+		}
+		p.pos++
+		if digits > 15 {
+			return nil, p.errorf("number is too long")
+		}
+	}
+
+	num := p.s[start:p.pos]
+	if !decimal {
+		return strconv.ParseInt(num, 10, 64)
+	}
+	if fracDigits == 0 || fracDigits > 3 {
+		return nil, p.errorf("decimal must have 1 to 3 fractional digits")
+	}
+	return strconv.ParseFloat(num, 64)
+}
+
+func (p *sfParser) parseString() (string, error) {
+	p.pos++ // opening quote
+	var sb strings.Builder
+	for !p.eof() {
+		c := p.s[p.pos]
+		p.pos++
+		switch {
+		case c == '\\':
+			if p.eof() {
+				return "", p.errorf("unterminated escape")
+			}
+			next := p.s[p.pos]
+			if next != '"' && next != '\\' {
+				return "", p.errorf("invalid escape")
+			}
+			sb.WriteByte(next)
+			p.pos++
+		case c == '"':
+			return sb.String(), nil
+		case c < 0x20 || c > 0x7e:
+			return "", p.errorf("invalid string character")
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated string")
+}
+
+func (p *sfParser) parseToken() Token {
+	start := p.pos
+	p.pos++
+	for !p.eof() && (isTChar(p.s[p.pos]) || p.s[p.pos] == ':' || p.s[p.pos] == '/') {
+		p.pos++
+	}
+	return Token(p.s[start:p.pos])
+}
+
+func (p *sfParser) parseByteSequence() ([]byte, error) {
+	p.pos++ // opening colon
+	end := strings.IndexByte(p.s[p.pos:], ':')
+	if end < 0 {
+		return nil, p.errorf("unterminated byte sequence")
+	}
+	encoded := p.s[p.pos : p.pos+end]
+	for i := 0; i < len(encoded); i++ {
+		c := encoded[i]
+		if !isAlpha(c) && !isDigit(c) && c != '+' && c != '/' && c != '=' {
+			return nil, p.errorf("invalid byte sequence character")
+		}
+	}
+	decoded, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(encoded, "="))
+	if err != nil {
+		return nil, p.errorf("invalid byte sequence")
+	}
+	p.pos += end + 1
+	return decoded, nil
+}
+
+func (p *sfParser) parseBoolean() (bool, error) {
+	p.pos++ // question mark
+	switch p.peek() {
+	case '1':
+		p.pos++
+		return true, nil
+	case '0':
+		p.pos++
+		return false, nil
+	}
+	return false, p.errorf("invalid boolean")
+}
+
+func (d Dictionary) Serialize() (string, error) {
+	var sb strings.Builder
+	for i, member := range d {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		if err := serializeKey(&sb, member.Key); err != nil {
+			return "", err
+		}
+		if item, ok := member.Value.(Item); ok && item.Value == true {
+			if err := serializeParams(&sb, item.Params); err != nil {
+				return "", err
+			}
+			continue
+		}
+		sb.WriteByte('=')
+		if err := serializeMember(&sb, member.Value); err != nil {
+			return "", err
+		}
+	}
+	return sb.String(), nil
+}
+
+func (l List) Serialize() (string, error) {
+	var sb strings.Builder
+	for i, member := range l {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		if err := serializeMember(&sb, member); err != nil {
+			return "", err
+		}
+	}
+	return sb.String(), nil
+}
+
+func (il InnerList) Serialize() (string, error) {
+	var sb strings.Builder
+	if err := serializeInnerList(&sb, il); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func (it Item) Serialize() (string, error) {
+	var sb strings.Builder
+	if err := serializeItem(&sb, it); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+func serializeMember(sb *strings.Builder, member any) error {
+	switch m := member.(type) {
+	case Item:
+		return serializeItem(sb, m)
+	case InnerList:
+		return serializeInnerList(sb, m)
+	}
+	return fmt.Errorf("%w: unsupported member type %T", ErrInvalidStructuredField, member)
+}
+
+func serializeInnerList(sb *strings.Builder, il InnerList) error {
+	sb.WriteByte('(')
+	for i, item := range il.Items {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		if err := serializeItem(sb, item); err != nil {
+			return err
+		}
+	}
+	sb.WriteByte(')')
+	return serializeParams(sb, il.Params)
+}
+
+func serializeItem(sb *strings.Builder, item Item) error {
+	if err := serializeBareItem(sb, item.Value); err != nil {
+		return err
+	}
+	return serializeParams(sb, item.Params)
+}
+
+func serializeParams(sb *strings.Builder, params Params) error {
+	for _, param := range params {
+		sb.WriteByte(';')
+		if err := serializeKey(sb, param.Key); err != nil {
+			return err
+		}
+		if param.Value == true {
+			continue
+		}
+		sb.WriteByte('=')
+		if err := serializeBareItem(sb, param.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func serializeKey(sb *strings.Builder, key string) error {
+	if key == "" || (!isLCAlpha(key[0]) && key[0] != '*') {
+		return fmt.Errorf("%w: invalid key %q", ErrInvalidStructuredField, key)
+	}
+	for i := 1; i < len(key); i++ {
+		if !isKeyChar(key[i]) {
+			return fmt.Errorf("%w: invalid key %q", ErrInvalidStructuredField, key)
+		}
+	}
+	sb.WriteString(key)
+	return nil
+}
+
+func serializeBareItem(sb *strings.Builder, value any) error {
+	switch v := value.(type) {
+	case int:
+		return serializeBareItem(sb, int64(v))
+	case int64:
+		if v > 999_999_999_999_999 || v < -999_999_999_999_999 {
+			return fmt.Errorf("%w: integer out of range", ErrInvalidStructuredField)
+		}
+		sb.WriteString(strconv.FormatInt(v, 10))
+	case float64:
+		rounded := math.RoundToEven(v*1000) / 1000
+		if math.Abs(rounded) >= 1e12 || math.IsNaN(v) {
+			return fmt.Errorf("%w: decimal out of range", ErrInvalidStructuredField)
+		}
+		s := strconv.FormatFloat(rounded, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			s += ".0"
+		}
+		sb.WriteString(s)
+	case string:
+		sb.WriteByte('"')
+		for i := 0; i < len(v); i++ {
+			c := v[i]
+			if c < 0x20 || c > 0x7e {
+				return fmt.Errorf("%w: invalid string character", ErrInvalidStructuredField)
+			}
+			if c == '"' || c == '\\' {
+				sb.WriteByte('\\')
+			}
+			sb.WriteByte(c)
+		}
+		sb.WriteByte('"')
+	case Token:
+		if v == "" || (!isAlpha(v[0]) && v[0] != '*') {
+			return fmt.Errorf("%w: invalid token %q", ErrInvalidStructuredField, v)
+		}
+		for i := 1; i < len(v); i++ {
+			if !isTChar(v[i]) && v[i] != ':' && v[i] != '/' {
+				return fmt.Errorf("%w: invalid token %q", ErrInvalidStructuredField, v)
+			}
+		}
+		sb.WriteString(string(v))
+	case []byte:
+		sb.WriteByte(':')
+		sb.WriteString(base64.StdEncoding.EncodeToString(v))
+		sb.WriteByte(':')
+	case bool:
+		if v {
+			sb.WriteString("?1")
+		} else {
+			sb.WriteString("?0")
+		}
+	default:
+		return fmt.Errorf("%w: unsupported bare item type %T", ErrInvalidStructuredField, value)
+	}
+	return nil
+}
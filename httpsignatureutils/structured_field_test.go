@@ -0,0 +1,153 @@
+package httpsignatureutils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseDictionary_RoundTrip(t *testing.T) {
+	tests := []string{
+		`sig1=("@method" "@target-uri");created=1618884473;keyid="test-key"`,
+		`sig-b21=();created=1618884473;keyid="test-key-rsa-pss";nonce="b3k2pp5k7z-50gnwp.yemd"`,
+		`a=1, b=?0, c, d=:cHJldGVuZCB0aGlzIGlzIGJpbmFyeQ==:, e=token/with:colon`,
+		`key="a \"quoted\" value;with=semicolons", num=-12.5;param`,
+		`sig1=("@query-param";name="id" "content-digest";sf "@status";req);tag="app-123";alg="ed25519";expires=1618884773`,
+	}
+
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			dict, err := ParseDictionary(tt)
+			if err != nil {
+				t.Fatalf("ParseDictionary returned error: %v", err)
+			}
+			serialized, err := dict.Serialize()
+			if err != nil {
+				t.Fatalf("Serialize returned error: %v", err)
+			}
+			if serialized != tt {
+				t.Errorf("round trip mismatch:\n got: %s\nwant: %s", serialized, tt)
+			}
+		})
+	}
+}
+
+func TestParseDictionary_Values(t *testing.T) {
+	dict, err := ParseDictionary(`a=1,  b="x;y", c=?1;p=2.25, d=(1 tok);q`)
+	if err != nil {
+		t.Fatalf("ParseDictionary returned error: %v", err)
+	}
+
+	expected := Dictionary{
+		{Key: "a", Value: Item{Value: int64(1)}},
+		{Key: "b", Value: Item{Value: "x;y"}},
+		{Key: "c", Value: Item{Value: true, Params: Params{{Key: "p", Value: 2.25}}}},
+		{Key: "d", Value: InnerList{
+			Items:  []Item{{Value: int64(1)}, {Value: Token("tok")}},
+			Params: Params{{Key: "q", Value: true}},
+		}},
+	}
+	if !reflect.DeepEqual(dict, expected) {
+		t.Errorf("unexpected dictionary:\n got: %#v\nwant: %#v", dict, expected)
+	}
+}
+
+func TestParseDictionary_Invalid(t *testing.T) {
+	tests := []string{
+		`a=1,`,
+		`A=1`,
+		`a=(1 2`,
+		`a="unterminated`,
+		`a="bad \x escape"`,
+		`a=:not base64!:`,
+		`a=1234567890123456`,
+		`a=1.2345`,
+		`a=?2`,
+		`a=1 b=2`,
+	}
+
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			if _, err := ParseDictionary(tt); !errors.Is(err, ErrInvalidStructuredField) {
+				t.Errorf("expected ErrInvalidStructuredField, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseSignatures_MultipleLabels(t *testing.T) {
+	input := `sig1=("@method");created=1;keyid="key-1", proxy_sig=("@method" "authorization");created=2;keyid="key;2";expires=3;nonce="n";tag="t";alg="ed25519"`
+	signature := `sig1=:AQI=:, proxy_sig=:AwQ=:`
+
+	sigs, err := ParseSignatures(input, signature)
+	if err != nil {
+		t.Fatalf("ParseSignatures returned error: %v", err)
+	}
+	if len(sigs) != 2 || sigs[0].Label != "sig1" || sigs[1].Label != "proxy_sig" {
+		t.Fatalf("unexpected signatures: %+v", sigs)
+	}
+
+	params := sigs[1].Params
+	keyID, _ := params.KeyID()
+	expires, _ := params.Expires()
+	nonce, _ := params.Nonce()
+	tag, _ := params.Tag()
+	alg, _ := params.Alg()
+	if keyID != "key;2" || expires != 3 || nonce != "n" || tag != "t" || alg != "ed25519" {
+		t.Errorf("unexpected params: %+v", params)
+	}
+	if !reflect.DeepEqual(sigs[1].Signature, []byte{3, 4}) {
+		t.Errorf("unexpected signature bytes: %v", sigs[1].Signature)
+	}
+
+	gotInput, gotSignature, err := FormatSignatures(sigs...)
+	if err != nil {
+		t.Fatalf("FormatSignatures returned error: %v", err)
+	}
+	if gotInput != input || gotSignature != signature {
+		t.Errorf("round trip mismatch:\n got: %s | %s\nwant: %s | %s", gotInput, gotSignature, input, signature)
+	}
+
+	if _, err := ParseSignatures(`sig1=("@method");created=1`, `other=:AQI=:`); !errors.Is(err, ErrMissingSignature) {
+		t.Errorf("expected ErrMissingSignature, got %v", err)
+	}
+}
+
+func TestValidateSignature_Label(t *testing.T) {
+	pub1, priv1, _ := ed25519.GenerateKey(rand.Reader)
+	pub2, priv2, _ := ed25519.GenerateKey(rand.Reader)
+
+	req, _ := http.NewRequest("GET", "https://example.com/resource", nil)
+
+	first, err := CreateSignatureHeaders(SignOptions{Request: req, PrivateKey: priv1, KeyID: "key-1"})
+	if err != nil {
+		t.Fatalf("CreateSignatureHeaders returned error: %v", err)
+	}
+	second, err := CreateSignatureHeaders(SignOptions{Request: req, PrivateKey: priv2, KeyID: "key-2", Label: "proxy"})
+	if err != nil {
+		t.Fatalf("CreateSignatureHeaders returned error: %v", err)
+	}
+
+	req.Header.Add("Signature-Input", first.SignatureInput)
+	req.Header.Add("Signature-Input", second.SignatureInput)
+	req.Header.Add("Signature", first.SignatureHeader)
+	req.Header.Add("Signature", second.SignatureHeader)
+
+	if err := ValidateSignature(NewValidationOptions(req, req.Header, pub1)); err != nil {
+		t.Errorf("expected first signature to validate: %v", err)
+	}
+
+	opts := NewValidationOptions(req, req.Header, pub2)
+	opts.Label = "proxy"
+	if err := ValidateSignature(opts); err != nil {
+		t.Errorf("expected proxy signature to validate: %v", err)
+	}
+
+	opts.PublicKey = pub1
+	if err := ValidateSignature(opts); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected ErrInvalidSignature, got %v", err)
+	}
+}
@@ -1,27 +1,48 @@
 package httpsignatureutils
 
 import (
-	"crypto/ed25519"
-	"encoding/base64"
+	"crypto"
 	"errors"
+	"fmt"
 	"net/http"
-	"strconv"
+	"slices"
 	"strings"
+	"time"
 )
 
 var (
-	ErrInvalidSignature     = errors.New("invalid signature")
-	ErrMissingSignature     = errors.New("missing signature")
+	ErrInvalidSignature      = errors.New("invalid signature")
+	ErrMissingSignature      = errors.New("missing signature")
 	ErrMissingSignatureInput = errors.New("missing signature input")
+	ErrSignatureExpired      = errors.New("signature expired")
+	ErrSignatureNotYetValid  = errors.New("signature created in the future")
+	ErrSignatureReplayed     = errors.New("signature replayed")
 )
 
 type ValidationOptions struct {
 	Request   *http.Request
+	Response  *http.Response // When set, its signature is validated. Request is the request it answers.
 	Headers   http.Header
-	PublicKey ed25519.PublicKey
+	PublicKey crypto.PublicKey // e.g. ed25519.PublicKey, *ecdsa.PublicKey, *rsa.PublicKey or HMACKey.
+	Label     string           // The signature to verify. Defaults to the first one.
+
+	// Algorithms limits the algorithms accepted, by name. By default any
+	// algorithm usable with PublicKey is accepted.
+	Algorithms []string
+
+	MaxAge         time.Duration    // Rejects signatures created longer ago. Zero disables the check.
+	ClockSkew      time.Duration    // Tolerance applied to created, expires and MaxAge.
+	RequireExpires bool             // Rejects signatures without an expires parameter.
+	ReplayCache    ReplayCache      // Rejects a (keyid, nonce or signature) that was already accepted.
+	Now            func() time.Time // Defaults to time.Now.
+
+	// AllowUncoveredBody accepts a body when the signature doesn't cover
+	// content-digest, leaving the body unauthenticated. A covered
+	// content-digest is still checked against the body.
+	AllowUncoveredBody bool
 }
 
-func NewValidationOptions(r *http.Request, headers http.Header, publicKey ed25519.PublicKey) *ValidationOptions {
+func NewValidationOptions(r *http.Request, headers http.Header, publicKey crypto.PublicKey) *ValidationOptions {
 	return &ValidationOptions{
 		Request:   r,
 		Headers:   headers,
@@ -29,77 +50,154 @@ func NewValidationOptions(r *http.Request, headers http.Header, publicKey ed2551
 	}
 }
 
-func parseSignatureInput(input string) ([]string, int64, string, error) {
-	input = strings.TrimPrefix(input, "sig1=")
-	
-	var components []string
-	var created int64
-	var keyID string
-	
-	for _, part := range strings.Split(input, ";") {
-		part = strings.TrimSpace(part)
-		
-		switch {
-		case strings.HasPrefix(part, "(") && strings.HasSuffix(part, ")"):
-			inner := part[1 : len(part)-1]
-			parsedComponents := strings.Fields(inner)
-			// strip quotes from each parsed component
-			for _, comp := range parsedComponents {
-				components = append(components, strings.Trim(comp, `"`))
-			}
-		case strings.HasPrefix(part, "created="):
-			val := strings.TrimPrefix(part, "created=")
-			t, err := strconv.ParseInt(val, 10, 64)
-			if err != nil {
-				return nil, 0, "", ErrInvalidSignature
-			}
-			created = t
-			
-		case strings.HasPrefix(part, "keyid="):
-			val := strings.TrimPrefix(part, "keyid=")
-			keyID = strings.Trim(val, `"`)
-		}
-	}
-	
-	if len(components) == 0 || created == 0 || keyID == "" {
-		return nil, 0, "", ErrInvalidSignature
+// NewResponseValidationOptions returns options validating the signature of
+// resp, with request-bound components taken from resp.Request.
+func NewResponseValidationOptions(resp *http.Response, publicKey crypto.PublicKey) *ValidationOptions {
+	return &ValidationOptions{
+		Request:   resp.Request,
+		Response:  resp,
+		Headers:   resp.Header,
+		PublicKey: publicKey,
 	}
-	
-	return components, created, keyID, nil
 }
 
+// ValidateSignature verifies the signature labeled opts.Label, or the first
+// signature in Signature-Input if no label is set. A message with a body must
+// cover content-digest unless AllowUncoveredBody is set, and the digest must
+// match the body. The signature must
+// also be fresh according to MaxAge, ClockSkew and RequireExpires and, when a
+// ReplayCache is set, not have been accepted before.
+func ValidateSignature(opts *ValidationOptions) error {
+	sigs, err := ParseSignatures(
+		strings.Join(opts.Headers.Values("Signature-Input"), ", "),
+		strings.Join(opts.Headers.Values("Signature"), ", "),
+	)
+	if err != nil {
+		return err
+	}
 
+	sig := sigs[0]
+	if opts.Label != "" {
+		found := false
+		for _, s := range sigs {
+			if s.Label == opts.Label {
+				sig, found = s, true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%w: no signature labeled %q", ErrMissingSignatureInput, opts.Label)
+		}
+	}
 
-func ValidateSignature(opts *ValidationOptions) error {
-	sigInput := opts.Headers.Get("Signature-Input")
-	if sigInput == "" {
-		return ErrMissingSignatureInput
+	if _, ok := sig.Params.Created(); !ok {
+		return fmt.Errorf("%w: missing created parameter", ErrInvalidSignature)
+	}
+	keyID, ok := sig.Params.KeyID()
+	if !ok {
+		return fmt.Errorf("%w: missing keyid parameter", ErrInvalidSignature)
 	}
 
-	components, created, keyID, err := parseSignatureInput(sigInput)
+	now := time.Now()
+	if opts.Now != nil {
+		now = opts.Now()
+	}
+	validUntil, err := checkFreshness(opts, sig.Params, now)
 	if err != nil {
 		return err
 	}
 
-	sig := opts.Headers.Get("Signature")
-	if sig == "" {
-		return ErrMissingSignature
+	msg := message{req: opts.Request, resp: opts.Response}
+	if err := verifySignedDigest(msg, sig.Params, opts.AllowUncoveredBody); err != nil {
+		return err
 	}
 
-	baseString, err := createSignatureBaseString(opts.Request, components, created, keyID)
+	baseString, err := messageSignatureBase(msg, sig.Params)
 	if err != nil {
 		return ErrInvalidSignature
 	}
 
-	sigBytes, err := base64.StdEncoding.DecodeString(sig)
-	if err != nil || len(sigBytes) != ed25519.SignatureSize {
-		return ErrInvalidSignature
+	algName, _ := sig.Params.Alg()
+	alg, err := resolveAlgorithm(algName, opts.PublicKey)
+	if err != nil {
+		return err
+	}
+	if len(opts.Algorithms) > 0 && !slices.Contains(opts.Algorithms, alg.Name()) {
+		return fmt.Errorf("%w: %s is not accepted", ErrUnsupportedAlgorithm, alg.Name())
+	}
+	if err := alg.Verify(opts.PublicKey, []byte(baseString), sig.Signature); err != nil {
+		return err
 	}
 
-	valid := ed25519.Verify(opts.PublicKey, []byte(baseString), sigBytes)
-	if !valid {
-		return ErrInvalidSignature
+	// Only verified signatures are recorded, so forgeries can't fill the cache.
+	nonce, _ := sig.Params.Nonce()
+	if opts.ReplayCache != nil && opts.ReplayCache.Seen(keyID, nonce, sig.Signature, validUntil) {
+		return ErrSignatureReplayed
+	}
+
+	return nil
+}
+
+// checkFreshness checks the created and expires parameters against now and
+// returns the time until which the signature is accepted, or the zero time if
+// it doesn't expire.
+func checkFreshness(opts *ValidationOptions, params SignatureParams, now time.Time) (time.Time, error) {
+	createdUnix, _ := params.Created()
+	created := time.Unix(createdUnix, 0)
+	if created.After(now.Add(opts.ClockSkew)) {
+		return time.Time{}, ErrSignatureNotYetValid
+	}
+
+	var validUntil time.Time
+	if opts.MaxAge > 0 {
+		validUntil = created.Add(opts.MaxAge + opts.ClockSkew)
+	}
+
+	expiresUnix, ok := params.Expires()
+	if ok {
+		if expiresUnix < createdUnix {
+			return time.Time{}, fmt.Errorf("%w: expires is before created", ErrInvalidSignature)
+		}
+		expires := time.Unix(expiresUnix, 0).Add(opts.ClockSkew)
+		if validUntil.IsZero() || expires.Before(validUntil) {
+			validUntil = expires
+		}
+	} else if opts.RequireExpires {
+		return time.Time{}, fmt.Errorf("%w: missing expires parameter", ErrInvalidSignature)
 	}
 
+	if !validUntil.IsZero() && now.After(validUntil) {
+		return time.Time{}, ErrSignatureExpired
+	}
+	return validUntil, nil
+}
+
+// verifySignedDigest checks the body of msg against a signed Content-Digest.
+func verifySignedDigest(msg message, params SignatureParams, allowUncovered bool) error {
+	covered := false
+	for _, c := range params.Components {
+		if _, req := c.Params.Get("req"); c.Name == "content-digest" && !req {
+			covered = true
+			break
+		}
+	}
+
+	var body []byte
+	var err error
+	if msg.resp != nil {
+		body, err = readBody(&msg.resp.Body)
+	} else {
+		body, err = readBody(&msg.req.Body)
+	}
+	if err != nil {
+		return err
+	}
+
+	if covered {
+		return verifyContentDigest(msg.header(), body)
+	}
+	if len(body) > 0 && !allowUncovered {
+		return fmt.Errorf("%w: content-digest is not covered by the signature", ErrMissingContentDigest)
+	}
 	return nil
 }
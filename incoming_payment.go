@@ -33,11 +33,13 @@ type IncomingPaymentGetPublicParams struct {
 type IncomingPaymentGetParams struct {
 	URL         string // The full URL of the incoming payment resource.
 	AccessToken string
+	TokenSource TokenSource // Used instead of AccessToken when set.
 }
 
 type IncomingPaymentListParams struct {
 	BaseURL       string // The base URL for the incoming payments collection.
 	AccessToken   string
+	TokenSource   TokenSource // Used instead of AccessToken when set.
 	WalletAddress string
 	Pagination    Pagination
 }
@@ -50,12 +52,14 @@ type IncomingPaymentListResponse struct {
 type IncomingPaymentCreateParams struct {
 	BaseURL     string // The base URL for creating an incoming payment
 	AccessToken string
+	TokenSource TokenSource // Used instead of AccessToken when set.
 	Payload     rs.CreateIncomingPaymentRequest
 }
 
 type IncomingPaymentCompleteParams struct {
 	URL         string // The incoming payment url
 	AccessToken string
+	TokenSource TokenSource // Used instead of AccessToken when set.
 }
 
 func (ip *IncomingPaymentService) GetPublic(ctx context.Context, params IncomingPaymentGetPublicParams) (rs.PublicIncomingPayment, error) {
@@ -97,7 +101,9 @@ func (ip *IncomingPaymentService) Get(ctx context.Context, params IncomingPaymen
 		return rs.IncomingPaymentWithMethods{}, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("GNAP %s", params.AccessToken))
+	if err := setAuthorization(req, params.AccessToken, params.TokenSource); err != nil {
+		return rs.IncomingPaymentWithMethods{}, err
+	}
 
 	resp, err := ip.DoSigned(req)
 	if err != nil {
@@ -142,7 +148,9 @@ func (ip *IncomingPaymentService) List(ctx context.Context, params IncomingPayme
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("GNAP %s", params.AccessToken))
+	if err := setAuthorization(req, params.AccessToken, params.TokenSource); err != nil {
+		return nil, err
+	}
 
 	resp, err := ip.DoSigned(req)
 	if err != nil {
@@ -180,7 +188,9 @@ func (ip *IncomingPaymentService) Create(ctx context.Context, params IncomingPay
 		return rs.IncomingPaymentWithMethods{}, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("GNAP %s", params.AccessToken))
+	if err := setAuthorization(req, params.AccessToken, params.TokenSource); err != nil {
+		return rs.IncomingPaymentWithMethods{}, err
+	}
 
 	resp, err := ip.DoSigned(req)
 	if err != nil {
@@ -212,7 +222,9 @@ func (ip *IncomingPaymentService) Complete(ctx context.Context, params IncomingP
 		return rs.IncomingPaymentWithMethods{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("GNAP %s", params.AccessToken))
+	if err := setAuthorization(req, params.AccessToken, params.TokenSource); err != nil {
+		return rs.IncomingPaymentWithMethods{}, err
+	}
 
 	resp, err := ip.DoSigned(req)
 	if err != nil {
@@ -0,0 +1,76 @@
+package openpayments
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidInteractHash = errors.New("invalid interaction hash")
+	ErrMissingInteractHash = errors.New("missing interaction hash or interact_ref")
+	ErrNotInteractFinish   = errors.New("grant has no interaction finish state")
+)
+
+// NewInteractNonce returns a random nonce suitable for interact.finish.nonce.
+func NewInteractNonce() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate interaction nonce: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ComputeInteractHash computes the interaction finish hash as defined by GNAP
+// (RFC 9635, section 4.2.3): the SHA-256 digest of the client nonce, the finish
+// nonce returned by the auth server, the interaction reference and the grant
+// endpoint URL, separated by newlines.
+func ComputeInteractHash(clientNonce string, serverNonce string, interactRef string, grantEndpoint string) string {
+	sum := interactHash(clientNonce, serverNonce, interactRef, grantEndpoint)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func interactHash(clientNonce string, serverNonce string, interactRef string, grantEndpoint string) [sha256.Size]byte {
+	return sha256.Sum256([]byte(clientNonce + "\n" + serverNonce + "\n" + interactRef + "\n" + grantEndpoint))
+}
+
+// VerifyInteractHash checks the hash received on the interaction finish
+// redirect. Both standard and URL-safe base64 encodings are accepted.
+func VerifyInteractHash(clientNonce string, serverNonce string, interactRef string, grantEndpoint string, hash string) error {
+	if hash == "" || interactRef == "" {
+		return ErrMissingInteractHash
+	}
+
+	received, err := decodeInteractHash(hash)
+	if err != nil {
+		return ErrInvalidInteractHash
+	}
+
+	expected := interactHash(clientNonce, serverNonce, interactRef, grantEndpoint)
+	if subtle.ConstantTimeCompare(received, expected[:]) != 1 {
+		return ErrInvalidInteractHash
+	}
+	return nil
+}
+
+func decodeInteractHash(hash string) ([]byte, error) {
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(hash); err == nil {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("hash is not base64 encoded")
+}
+
+// VerifyInteractFinish checks the interact_ref and hash received on the
+// interaction finish redirect against the nonces recorded when the grant was
+// requested. It must succeed before the grant is continued.
+func (gr *Grant) VerifyInteractFinish(interactRef string, hash string) error {
+	if gr.Interact == nil || gr.InteractNonce == "" || gr.GrantEndpoint == "" {
+		return ErrNotInteractFinish
+	}
+	return VerifyInteractHash(gr.InteractNonce, gr.Interact.Finish, interactRef, gr.GrantEndpoint, hash)
+}
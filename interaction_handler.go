@@ -0,0 +1,259 @@
+package openpayments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	as "github.com/interledger/open-payments-go/generated/authserver"
+)
+
+var (
+	ErrPendingGrantNotFound = errors.New("pending grant not found")
+	ErrInteractionRejected  = errors.New("interaction was rejected by the resource owner")
+)
+
+// DefaultInteractNonceParam is the query parameter InteractionHandler reads
+// the client nonce from unless configured otherwise.
+const DefaultInteractNonceParam = "nonce"
+
+// DefaultPendingGrantTTL is how long a MemoryPendingGrantStore keeps a grant
+// whose finish redirect hasn't arrived.
+const DefaultPendingGrantTTL = 30 * time.Minute
+
+// PendingGrantStore holds interactive grants that are waiting for the
+// interaction finish redirect, keyed by the client nonce sent in
+// interact.finish.
+type PendingGrantStore interface {
+	Put(ctx context.Context, nonce string, grant Grant) error
+	Get(ctx context.Context, nonce string) (Grant, error) // Returns ErrPendingGrantNotFound if missing.
+	// Take removes and returns the grant, so only one of several concurrent
+	// callers gets it. Returns ErrPendingGrantNotFound if missing.
+	Take(ctx context.Context, nonce string) (Grant, error)
+}
+
+type pendingGrant struct {
+	grant   Grant
+	expires time.Time
+}
+
+// MemoryPendingGrantStore is a PendingGrantStore in process memory. Grants
+// expire after its TTL.
+type MemoryPendingGrantStore struct {
+	mu     sync.Mutex
+	grants map[string]pendingGrant
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+// MemoryPendingGrantStoreOption configures a MemoryPendingGrantStore.
+type MemoryPendingGrantStoreOption func(*MemoryPendingGrantStore)
+
+// WithPendingGrantTTL sets how long grants are kept. It defaults to
+// DefaultPendingGrantTTL.
+func WithPendingGrantTTL(ttl time.Duration) MemoryPendingGrantStoreOption {
+	return func(s *MemoryPendingGrantStore) {
+		s.ttl = ttl
+	}
+}
+
+func NewMemoryPendingGrantStore(opts ...MemoryPendingGrantStoreOption) *MemoryPendingGrantStore {
+	s := &MemoryPendingGrantStore{
+		grants: make(map[string]pendingGrant),
+		ttl:    DefaultPendingGrantTTL,
+		now:    time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *MemoryPendingGrantStore) Put(_ context.Context, nonce string, grant Grant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for n, pending := range s.grants {
+		if !now.Before(pending.expires) {
+			delete(s.grants, n)
+		}
+	}
+	s.grants[nonce] = pendingGrant{grant: grant, expires: now.Add(s.ttl)}
+	return nil
+}
+
+func (s *MemoryPendingGrantStore) Get(_ context.Context, nonce string) (Grant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending, ok := s.grants[nonce]
+	if !ok || !s.now().Before(pending.expires) {
+		return Grant{}, ErrPendingGrantNotFound
+	}
+	return pending.grant, nil
+}
+
+func (s *MemoryPendingGrantStore) Take(_ context.Context, nonce string) (Grant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending, ok := s.grants[nonce]
+	delete(s.grants, nonce)
+	if !ok || !s.now().Before(pending.expires) {
+		return Grant{}, ErrPendingGrantNotFound
+	}
+	return pending.grant, nil
+}
+
+// InteractionHandler is an http.Handler for the interaction finish redirect.
+// It looks up the pending grant by client nonce, verifies the finish hash,
+// continues the grant and passes the result to the grant callback.
+//
+// By default the client nonce is read from the "nonce" query parameter, so
+// the interact request should be built with NewInteractionRedirect.
+type InteractionHandler struct {
+	grants  *GrantService
+	store   PendingGrantStore
+	onGrant func(w http.ResponseWriter, r *http.Request, grant Grant)
+	onError func(w http.ResponseWriter, r *http.Request, err error)
+	nonce   func(r *http.Request) string
+}
+
+// InteractionHandlerOption is used to configure optional behavior for the interaction handler.
+type InteractionHandlerOption func(*InteractionHandler)
+
+// WithInteractionErrorHandler sets the function called when the callback
+// can't be completed. The default writes a plain text error with a status
+// derived from the error.
+func WithInteractionErrorHandler(onError func(w http.ResponseWriter, r *http.Request, err error)) InteractionHandlerOption {
+	return func(h *InteractionHandler) {
+		h.onError = onError
+	}
+}
+
+// WithInteractionNonce sets how the client nonce is recovered from the
+// callback request, e.g. from a session cookie.
+func WithInteractionNonce(nonce func(r *http.Request) string) InteractionHandlerOption {
+	return func(h *InteractionHandler) {
+		h.nonce = nonce
+	}
+}
+
+func NewInteractionHandler(grants *GrantService, store PendingGrantStore, onGrant func(w http.ResponseWriter, r *http.Request, grant Grant), opts ...InteractionHandlerOption) *InteractionHandler {
+	h := &InteractionHandler{
+		grants:  grants,
+		store:   store,
+		onGrant: onGrant,
+		onError: writeInteractionError,
+		nonce: func(r *http.Request) string {
+			return r.URL.Query().Get(DefaultInteractNonceParam)
+		},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// InteractFinishURI adds the client nonce to callbackURL so the default
+// InteractionHandler can find the pending grant. The same nonce must be sent
+// in interact.finish, so it can't be left for GrantService.Request to
+// generate.
+func InteractFinishURI(callbackURL string, nonce string) (string, error) {
+	if nonce == "" {
+		return "", fmt.Errorf("missing interaction nonce")
+	}
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid callback URL: %w", err)
+	}
+	query := u.Query()
+	query.Set(DefaultInteractNonceParam, nonce)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// NewInteractionRedirect returns a redirect interact request whose finish URI
+// is callbackURL with a freshly generated client nonce, as the default
+// InteractionHandler expects.
+func NewInteractionRedirect(callbackURL string) (*as.InteractRequest, error) {
+	nonce, err := NewInteractNonce()
+	if err != nil {
+		return nil, err
+	}
+	finishURI, err := InteractFinishURI(callbackURL, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return NewRedirectInteract(finishURI, nonce), nil
+}
+
+// Track stores an interactive grant returned by GrantService.Request until
+// its finish redirect arrives.
+func (h *InteractionHandler) Track(ctx context.Context, grant Grant) error {
+	if !grant.IsInteractive() || grant.InteractNonce == "" {
+		return ErrNotInteractFinish
+	}
+	return h.store.Put(ctx, grant.InteractNonce, grant)
+}
+
+func (h *InteractionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	nonce := h.nonce(r)
+	if nonce == "" {
+		h.onError(w, r, ErrPendingGrantNotFound)
+		return
+	}
+
+	pending, err := h.store.Get(ctx, nonce)
+	if err != nil {
+		h.onError(w, r, err)
+		return
+	}
+
+	// A rejection carries no hash, so anyone who saw the finish URI could
+	// forge one. The grant is left in the store rather than cancelled.
+	interactRef := query.Get("interact_ref")
+	if interactRef == "" && query.Get("result") != "" {
+		h.onError(w, r, fmt.Errorf("%w: %s", ErrInteractionRejected, query.Get("result")))
+		return
+	}
+
+	if err := pending.VerifyInteractFinish(interactRef, query.Get("hash")); err != nil {
+		h.onError(w, r, err)
+		return
+	}
+	if _, err := h.store.Take(ctx, nonce); err != nil {
+		h.onError(w, r, err)
+		return
+	}
+
+	grant, err := h.grants.Continue(ctx, GrantContinueParams{
+		URL:         pending.Continue.Uri,
+		AccessToken: pending.Continue.AccessToken.Value,
+		InteractRef: interactRef,
+	})
+	if err != nil {
+		h.onError(w, r, err)
+		return
+	}
+
+	h.onGrant(w, r, grant)
+}
+
+func writeInteractionError(w http.ResponseWriter, _ *http.Request, err error) {
+	status := http.StatusBadGateway
+	switch {
+	case errors.Is(err, ErrPendingGrantNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrMissingInteractHash):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrInvalidInteractHash), errors.Is(err, ErrNotInteractFinish), errors.Is(err, ErrInteractionRejected):
+		status = http.StatusForbidden
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,182 @@
+package openpayments_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	openpayments "github.com/interledger/open-payments-go"
+	as "github.com/interledger/open-payments-go/generated/authserver"
+	"github.com/stretchr/testify/assert"
+)
+
+type interactionFixture struct {
+	client       *openpayments.AuthenticatedClient
+	handler      *openpayments.InteractionHandler
+	authServer   *httptest.Server
+	callback     *httptest.Server
+	grant        openpayments.Grant
+	continueRefs []string
+	granted      []openpayments.Grant
+}
+
+func newInteractionFixture(t *testing.T) *interactionFixture {
+	t.Helper()
+	f := &interactionFixture{}
+
+	f.authServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			_ = json.NewEncoder(w).Encode(openpayments.Grant{
+				Interact: &as.InteractResponse{Redirect: "https://auth.example.com/interact/1", Finish: "server-nonce"},
+				Continue: as.Continue{
+					Uri: f.authServer.URL + "/continue/1",
+					AccessToken: struct {
+						Value string `json:"value"`
+					}{Value: "continue-token"},
+				},
+			})
+		case "/continue/1":
+			var body as.ContinuationRequest
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			f.continueRefs = append(f.continueRefs, *body.InteractRef)
+			_ = json.NewEncoder(w).Encode(openpayments.Grant{
+				AccessToken: &as.AccessToken{Value: "access-token", Manage: f.authServer.URL + "/token/1"},
+				Continue:    as.Continue{Uri: f.authServer.URL + "/continue/1"},
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(f.authServer.Close)
+
+	var err error
+	f.client, err = openpayments.NewAuthenticatedClient(walletAddress, pk, keyID, openpayments.WithHTTPClientAuthed(f.authServer.Client()))
+	assert.NoError(t, err)
+
+	f.handler = openpayments.NewInteractionHandler(
+		f.client.Grant,
+		openpayments.NewMemoryPendingGrantStore(),
+		func(w http.ResponseWriter, r *http.Request, grant openpayments.Grant) {
+			f.granted = append(f.granted, grant)
+			w.WriteHeader(http.StatusOK)
+		},
+	)
+	f.callback = httptest.NewServer(f.handler)
+	t.Cleanup(f.callback.Close)
+
+	interact, err := openpayments.NewInteractionRedirect(f.callback.URL + "/finish")
+	assert.NoError(t, err)
+
+	body, err := openpayments.NewOutgoingPaymentGrantRequest(openpayments.OutgoingPaymentGrantParams{
+		Actions:    []as.AccessOutgoingActions{as.AccessOutgoingActionsCreate},
+		Identifier: walletAddress,
+		Interact:   interact,
+	})
+	assert.NoError(t, err)
+
+	f.grant, err = f.client.Grant.Request(context.Background(), openpayments.GrantRequestParams{
+		URL:         f.authServer.URL + "/",
+		RequestBody: body,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, f.handler.Track(context.Background(), f.grant))
+
+	return f
+}
+
+func (f *interactionFixture) redirect(t *testing.T, query url.Values) *http.Response {
+	t.Helper()
+	u, err := url.Parse(f.callback.URL + "/finish")
+	assert.NoError(t, err)
+	q := u.Query()
+	q.Set(openpayments.DefaultInteractNonceParam, f.grant.InteractNonce)
+	for k, v := range query {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+
+	resp, err := http.Get(u.String())
+	assert.NoError(t, err)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp
+}
+
+func TestInteractionHandler_CompletesGrant(t *testing.T) {
+	f := newInteractionFixture(t)
+
+	hash := openpayments.ComputeInteractHash(f.grant.InteractNonce, "server-nonce", "ref-123", f.authServer.URL+"/")
+	resp := f.redirect(t, url.Values{"interact_ref": {"ref-123"}, "hash": {hash}})
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, []string{"ref-123"}, f.continueRefs)
+	assert.Len(t, f.granted, 1)
+	assert.Equal(t, "access-token", f.granted[0].AccessToken.Value)
+
+	// the pending grant is consumed
+	resp = f.redirect(t, url.Values{"interact_ref": {"ref-123"}, "hash": {hash}})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestInteractionHandler_RejectsInvalidHash(t *testing.T) {
+	f := newInteractionFixture(t)
+
+	hash := openpayments.ComputeInteractHash(f.grant.InteractNonce, "server-nonce", "ref-123", f.authServer.URL+"/")
+	resp := f.redirect(t, url.Values{"interact_ref": {"injected-ref"}, "hash": {hash}})
+
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Empty(t, f.continueRefs)
+	assert.Empty(t, f.granted)
+
+	resp = f.redirect(t, url.Values{"interact_ref": {"ref-123"}})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestInteractionHandler_Rejected(t *testing.T) {
+	f := newInteractionFixture(t)
+
+	resp := f.redirect(t, url.Values{"result": {"grant_rejected"}})
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Empty(t, f.continueRefs)
+
+	// an unauthenticated rejection doesn't cancel the pending grant
+	hash := openpayments.ComputeInteractHash(f.grant.InteractNonce, "server-nonce", "ref-123", f.authServer.URL+"/")
+	resp = f.redirect(t, url.Values{"interact_ref": {"ref-123"}, "hash": {hash}})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Len(t, f.granted, 1)
+}
+
+func TestMemoryPendingGrantStore(t *testing.T) {
+	ctx := context.Background()
+	store := openpayments.NewMemoryPendingGrantStore()
+	assert.NoError(t, store.Put(ctx, "nonce-1", openpayments.Grant{InteractNonce: "nonce-1"}))
+
+	grant, err := store.Take(ctx, "nonce-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "nonce-1", grant.InteractNonce)
+	_, err = store.Take(ctx, "nonce-1")
+	assert.ErrorIs(t, err, openpayments.ErrPendingGrantNotFound)
+
+	store = openpayments.NewMemoryPendingGrantStore(openpayments.WithPendingGrantTTL(0))
+	assert.NoError(t, store.Put(ctx, "nonce-1", openpayments.Grant{}))
+	_, err = store.Get(ctx, "nonce-1")
+	assert.ErrorIs(t, err, openpayments.ErrPendingGrantNotFound)
+}
+
+func TestInteractFinishURI_RequiresNonce(t *testing.T) {
+	_, err := openpayments.InteractFinishURI("https://client.example/finish", "")
+	assert.Error(t, err)
+
+	interact, err := openpayments.NewInteractionRedirect("https://client.example/finish?session=1")
+	assert.NoError(t, err)
+	finishURI, err := url.Parse(interact.Finish.Uri)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, interact.Finish.Nonce)
+	assert.Equal(t, interact.Finish.Nonce, finishURI.Query().Get(openpayments.DefaultInteractNonceParam))
+	assert.Equal(t, "1", finishURI.Query().Get("session"))
+}
@@ -0,0 +1,81 @@
+package openpayments_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	openpayments "github.com/interledger/open-payments-go"
+	as "github.com/interledger/open-payments-go/generated/authserver"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeInteractHash(t *testing.T) {
+	sum := sha256.Sum256([]byte("client-nonce\nserver-nonce\nref-123\nhttps://auth.example.com/"))
+	expected := base64.StdEncoding.EncodeToString(sum[:])
+
+	assert.Equal(t, expected, openpayments.ComputeInteractHash("client-nonce", "server-nonce", "ref-123", "https://auth.example.com/"))
+}
+
+func TestVerifyInteractHash(t *testing.T) {
+	hash := openpayments.ComputeInteractHash("client-nonce", "server-nonce", "ref-123", "https://auth.example.com/")
+	raw, err := base64.StdEncoding.DecodeString(hash)
+	assert.NoError(t, err)
+
+	assert.NoError(t, openpayments.VerifyInteractHash("client-nonce", "server-nonce", "ref-123", "https://auth.example.com/", hash))
+	assert.NoError(t, openpayments.VerifyInteractHash("client-nonce", "server-nonce", "ref-123", "https://auth.example.com/", base64.RawURLEncoding.EncodeToString(raw)))
+
+	assert.ErrorIs(t, openpayments.VerifyInteractHash("client-nonce", "server-nonce", "injected-ref", "https://auth.example.com/", hash), openpayments.ErrInvalidInteractHash)
+	assert.ErrorIs(t, openpayments.VerifyInteractHash("other-nonce", "server-nonce", "ref-123", "https://auth.example.com/", hash), openpayments.ErrInvalidInteractHash)
+	assert.ErrorIs(t, openpayments.VerifyInteractHash("client-nonce", "server-nonce", "ref-123", "https://auth.example.com/", "not base64!"), openpayments.ErrInvalidInteractHash)
+	assert.ErrorIs(t, openpayments.VerifyInteractHash("client-nonce", "server-nonce", "", "https://auth.example.com/", hash), openpayments.ErrMissingInteractHash)
+}
+
+func TestGrantRequest_TracksInteractNonce(t *testing.T) {
+	var sentNonce string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Interact as.InteractRequest `json:"interact"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		sentNonce = body.Interact.Finish.Nonce
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(openpayments.Grant{
+			Interact: &as.InteractResponse{Redirect: "https://auth.example.com/interact/1", Finish: "server-nonce"},
+			Continue: as.Continue{Uri: "https://auth.example.com/continue/1"},
+		})
+	}))
+	defer server.Close()
+
+	client, err := openpayments.NewAuthenticatedClient(walletAddress, pk, keyID, openpayments.WithHTTPClientAuthed(server.Client()))
+	assert.NoError(t, err)
+
+	body, err := openpayments.NewOutgoingPaymentGrantRequest(openpayments.OutgoingPaymentGrantParams{
+		Actions:    []as.AccessOutgoingActions{as.AccessOutgoingActionsCreate},
+		Identifier: walletAddress,
+		Interact:   openpayments.NewRedirectInteract("https://client.example.com/finish", ""),
+	})
+	assert.NoError(t, err)
+
+	grant, err := client.Grant.Request(context.Background(), openpayments.GrantRequestParams{
+		URL:         server.URL,
+		RequestBody: body,
+	})
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, sentNonce)
+	assert.Equal(t, sentNonce, grant.InteractNonce)
+	assert.Equal(t, server.URL, grant.GrantEndpoint)
+
+	hash := openpayments.ComputeInteractHash(sentNonce, "server-nonce", "ref-123", server.URL)
+	assert.NoError(t, grant.VerifyInteractFinish("ref-123", hash))
+	assert.ErrorIs(t, grant.VerifyInteractFinish("other-ref", hash), openpayments.ErrInvalidInteractHash)
+
+	nonInteractive := openpayments.Grant{}
+	assert.ErrorIs(t, nonInteractive.VerifyInteractFinish("ref-123", hash), openpayments.ErrNotInteractFinish)
+}
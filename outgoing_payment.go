@@ -19,11 +19,13 @@ type OutgoingPaymentService struct {
 type OutgoingPaymentGetParams struct {
 	URL         string // The full URL of the outgoing payment resource.
 	AccessToken string
+	TokenSource TokenSource // Used instead of AccessToken when set.
 }
 
 type OutgoingPaymentListParams struct {
 	BaseURL       string // The base URL for the outgoing payments collection.
 	AccessToken   string
+	TokenSource   TokenSource // Used instead of AccessToken when set.
 	WalletAddress string
 	Pagination    Pagination
 }
@@ -36,12 +38,14 @@ type OutgoingPaymentListResponse struct {
 type OutgoingPaymentCreateParams struct {
 	BaseURL     string // The base URL for creating an outgoing payment
 	AccessToken string
+	TokenSource TokenSource // Used instead of AccessToken when set.
 	Payload     rs.CreateOutgoingPaymentRequest
 }
 
 type OutgoingPaymentGrantGetParams struct {
 	BaseURL     string // The base URL of the wallet address.
 	AccessToken string
+	TokenSource TokenSource // Used instead of AccessToken when set.
 }
 
 type OutgoingPaymentGrantSpentAmounts struct {
@@ -50,7 +54,7 @@ type OutgoingPaymentGrantSpentAmounts struct {
 }
 
 func (op *OutgoingPaymentService) Get(ctx context.Context, params OutgoingPaymentGetParams) (rs.OutgoingPayment, error) {
-	if params.URL == "" || params.AccessToken == "" {
+	if params.URL == "" || (params.AccessToken == "" && params.TokenSource == nil) {
 		return rs.OutgoingPayment{}, fmt.Errorf("missing required url or access token")
 	}
 	if !strings.Contains(params.URL, "outgoing-payments/") {
@@ -62,7 +66,9 @@ func (op *OutgoingPaymentService) Get(ctx context.Context, params OutgoingPaymen
 		return rs.OutgoingPayment{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("GNAP %s", params.AccessToken))
+	if err := setAuthorization(req, params.AccessToken, params.TokenSource); err != nil {
+		return rs.OutgoingPayment{}, err
+	}
 
 	resp, err := op.DoSigned(req)
 	if err != nil {
@@ -84,7 +90,7 @@ func (op *OutgoingPaymentService) Get(ctx context.Context, params OutgoingPaymen
 }
 
 func (op *OutgoingPaymentService) List(ctx context.Context, params OutgoingPaymentListParams) (*OutgoingPaymentListResponse, error) {
-	if params.BaseURL == "" || params.AccessToken == "" || params.WalletAddress == "" {
+	if params.BaseURL == "" || (params.AccessToken == "" && params.TokenSource == nil) || params.WalletAddress == "" {
 		return nil, fmt.Errorf("missing required base url, access token, or wallet address")
 	}
 
@@ -111,7 +117,9 @@ func (op *OutgoingPaymentService) List(ctx context.Context, params OutgoingPayme
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("GNAP %s", params.AccessToken))
+	if err := setAuthorization(req, params.AccessToken, params.TokenSource); err != nil {
+		return nil, err
+	}
 
 	resp, err := op.DoSigned(req)
 	if err != nil {
@@ -134,7 +142,7 @@ func (op *OutgoingPaymentService) List(ctx context.Context, params OutgoingPayme
 
 // TODO: ensure this works with/without quoteId in the params.payload
 func (op *OutgoingPaymentService) Create(ctx context.Context, params OutgoingPaymentCreateParams) (rs.OutgoingPaymentWithSpentAmounts, error) {
-	if params.BaseURL == "" || params.AccessToken == "" {
+	if params.BaseURL == "" || (params.AccessToken == "" && params.TokenSource == nil) {
 		return rs.OutgoingPaymentWithSpentAmounts{}, fmt.Errorf("missing required base url or access token")
 	}
 
@@ -153,7 +161,9 @@ func (op *OutgoingPaymentService) Create(ctx context.Context, params OutgoingPay
 		return rs.OutgoingPaymentWithSpentAmounts{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("GNAP %s", params.AccessToken))
+	if err := setAuthorization(req, params.AccessToken, params.TokenSource); err != nil {
+		return rs.OutgoingPaymentWithSpentAmounts{}, err
+	}
 
 	resp, err := op.DoSigned(req)
 	if err != nil {
@@ -175,7 +185,7 @@ func (op *OutgoingPaymentService) Create(ctx context.Context, params OutgoingPay
 }
 
 func (op *OutgoingPaymentService) GetGrantSpentAmounts(ctx context.Context, params OutgoingPaymentGrantGetParams) (OutgoingPaymentGrantSpentAmounts, error) {
-	if params.BaseURL == "" || params.AccessToken == "" {
+	if params.BaseURL == "" || (params.AccessToken == "" && params.TokenSource == nil) {
 		return OutgoingPaymentGrantSpentAmounts{}, fmt.Errorf("missing required base url or access token")
 	}
 
@@ -189,7 +199,9 @@ func (op *OutgoingPaymentService) GetGrantSpentAmounts(ctx context.Context, para
 		return OutgoingPaymentGrantSpentAmounts{}, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("GNAP %s", params.AccessToken))
+	if err := setAuthorization(req, params.AccessToken, params.TokenSource); err != nil {
+		return OutgoingPaymentGrantSpentAmounts{}, err
+	}
 
 	resp, err := op.DoSigned(req)
 	if err != nil {
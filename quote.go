@@ -19,11 +19,13 @@ type QuoteService struct {
 type QuoteGetParams struct {
 	URL         string // The full URL of the quote resource.
 	AccessToken string
+	TokenSource TokenSource // Used instead of AccessToken when set.
 }
 
 type QuoteCreateParams struct {
 	BaseURL     string // The base URL for creating a quote (e.g., wallet address URL).
 	AccessToken string
+	TokenSource TokenSource // Used instead of AccessToken when set.
 	// TODO: cant use rs.CreateQuoteRequest (unexported `union`). Consumers should pass one of
 	// the named variants directly: rs.CreateQuoteRequestByReceiver, rs.CreateQuoteRequestWithReceiveAmount,
 	// or rs.CreateQuoteRequestWithDebitAmount.
@@ -36,7 +38,9 @@ func (qs *QuoteService) Get(ctx context.Context, params QuoteGetParams) (rs.Quot
 		return rs.Quote{}, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("GNAP %s", params.AccessToken))
+	if err := setAuthorization(req, params.AccessToken, params.TokenSource); err != nil {
+		return rs.Quote{}, err
+	}
 
 	resp, err := qs.DoSigned(req)
 	if err != nil {
@@ -73,7 +77,9 @@ func (qs *QuoteService) Create(ctx context.Context, params QuoteCreateParams) (r
 		return rs.Quote{}, err
 	}
 
-	req.Header.Set("Authorization", fmt.Sprintf("GNAP %s", params.AccessToken))
+	if err := setAuthorization(req, params.AccessToken, params.TokenSource); err != nil {
+		return rs.Quote{}, err
+	}
 
 	resp, err := qs.DoSigned(req)
 	if err != nil {
@@ -0,0 +1,483 @@
+package openpayments
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	as "github.com/interledger/open-payments-go/generated/authserver"
+	was "github.com/interledger/open-payments-go/generated/walletaddressserver"
+	"github.com/interledger/open-payments-go/httpsignatureutils"
+)
+
+var (
+	ErrUnknownClient       = errors.New("unable to determine the client that signed the request")
+	ErrUnknownKey          = errors.New("signing key is not in the published key set")
+	ErrRequestBodyTooLarge = errors.New("request body is too large to verify")
+)
+
+// DefaultMaxBodySize is the largest request body Verify reads unless
+// WithMaxBodySize is used.
+const DefaultMaxBodySize = 1 << 20
+
+// DefaultKeyCacheTTL is how long a client's JWKS is cached unless
+// WithKeyCacheTTL is used.
+const DefaultKeyCacheTTL = 5 * time.Minute
+
+// DefaultKeyCacheSize is how many clients' JWKS are cached unless
+// WithKeyCacheSize is used.
+const DefaultKeyCacheSize = 1000
+
+// minKeyRefresh limits refetching a cached JWKS when a request names an
+// unknown key ID, so unknown key IDs can't be used to hammer the client's
+// wallet address.
+const minKeyRefresh = 10 * time.Second
+
+// ClientIdentity is the client that signed a verified request.
+type ClientIdentity struct {
+	WalletAddress string // Empty for directed identity clients.
+	KeyID         string
+	Key           crypto.PublicKey
+}
+
+type clientIdentityContextKey struct{}
+
+// ClientIdentityFromContext returns the identity stored by
+// SignatureVerifier.Middleware.
+func ClientIdentityFromContext(ctx context.Context) (ClientIdentity, bool) {
+	identity, ok := ctx.Value(clientIdentityContextKey{}).(ClientIdentity)
+	return identity, ok
+}
+
+// ClientResolver returns the wallet address of the client that signed r with
+// keyID, e.g. by looking up the grant of the request's access token.
+type ClientResolver func(r *http.Request, keyID string) (walletAddress string, err error)
+
+type cachedKeySet struct {
+	walletAddress string
+	keys          []was.JsonWebKey
+	fetchedAt     time.Time
+}
+
+// SignatureVerifier verifies the HTTP message signatures of requests sent by
+// Open Payments clients. The client's wallet address is taken from the client
+// field of a grant request body or, for requests with an access token, from
+// the ClientResolver.
+// Its keys are fetched from the wallet address' JWKS and cached for a bounded
+// number of clients. Signed
+// responses can be verified the same way with VerifyResponse.
+type SignatureVerifier struct {
+	walletAddress *WalletAddressService
+	resolver      ClientResolver
+	baseURL       *url.URL
+	ttl           time.Duration
+	cacheSize     int
+	maxBodySize   int64
+	validation    func(*httpsignatureutils.ValidationOptions)
+	onError       func(w http.ResponseWriter, r *http.Request, err error)
+	now           func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]*list.Element
+	order *list.List // Front is the most recently fetched.
+}
+
+// SignatureVerifierOption is used to configure optional behavior for the
+// signature verifier.
+type SignatureVerifierOption func(*SignatureVerifier)
+
+// WithClientResolver sets how the client's wallet address is found for
+// requests that don't carry it in a grant request body. It is required to
+// verify requests with a GNAP access token.
+func WithClientResolver(resolver ClientResolver) SignatureVerifierOption {
+	return func(v *SignatureVerifier) {
+		v.resolver = resolver
+	}
+}
+
+// WithBaseURL sets the scheme and host used to rebuild @target-uri, for
+// servers behind a proxy or load balancer. By default they are taken from the
+// incoming request.
+func WithBaseURL(baseURL string) SignatureVerifierOption {
+	return func(v *SignatureVerifier) {
+		u, err := url.Parse(baseURL)
+		if err == nil {
+			v.baseURL = u
+		}
+	}
+}
+
+// WithKeyCacheTTL sets how long a client's JWKS is cached.
+func WithKeyCacheTTL(ttl time.Duration) SignatureVerifierOption {
+	return func(v *SignatureVerifier) {
+		v.ttl = ttl
+	}
+}
+
+// WithKeyCacheSize sets how many clients' JWKS are cached. When full, the
+// least recently fetched is dropped.
+func WithKeyCacheSize(n int) SignatureVerifierOption {
+	return func(v *SignatureVerifier) {
+		v.cacheSize = n
+	}
+}
+
+// WithMaxBodySize sets the largest request body Verify reads to check its
+// content digest. Larger requests are rejected with ErrRequestBodyTooLarge.
+func WithMaxBodySize(n int64) SignatureVerifierOption {
+	return func(v *SignatureVerifier) {
+		v.maxBodySize = n
+	}
+}
+
+// WithSignatureValidation lets configure adjust the validation options of
+// each request, e.g. to set MaxAge or a ReplayCache.
+func WithSignatureValidation(configure func(*httpsignatureutils.ValidationOptions)) SignatureVerifierOption {
+	return func(v *SignatureVerifier) {
+		v.validation = configure
+	}
+}
+
+// WithVerificationErrorHandler sets the handler Middleware calls for requests
+// that fail verification. By default they get a 401 response.
+func WithVerificationErrorHandler(handler func(w http.ResponseWriter, r *http.Request, err error)) SignatureVerifierOption {
+	return func(v *SignatureVerifier) {
+		v.onError = handler
+	}
+}
+
+// NewSignatureVerifier returns a verifier that fetches client keys with
+// walletAddress, e.g. the WalletAddress service of a Client.
+func NewSignatureVerifier(walletAddress *WalletAddressService, opts ...SignatureVerifierOption) *SignatureVerifier {
+	if walletAddress == nil {
+		walletAddress = &WalletAddressService{DoUnsigned: http.DefaultClient.Do}
+	}
+	v := &SignatureVerifier{
+		walletAddress: walletAddress,
+		ttl:           DefaultKeyCacheTTL,
+		cacheSize:     DefaultKeyCacheSize,
+		maxBodySize:   DefaultMaxBodySize,
+		now:           time.Now,
+		cache:         make(map[string]*list.Element),
+		order:         list.New(),
+		onError: func(w http.ResponseWriter, r *http.Request, err error) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		},
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	if v.cacheSize <= 0 {
+		v.cacheSize = DefaultKeyCacheSize
+	}
+	return v
+}
+
+// Middleware verifies the signature of each request before passing it to
+// next, with the verified ClientIdentity in the request context.
+func (v *SignatureVerifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := v.Verify(r)
+		if err != nil {
+			v.onError(w, r, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), clientIdentityContextKey{}, identity)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Verify checks the signature and content digest of r and returns the client
+// that signed it. The body of r is left readable.
+func (v *SignatureVerifier) Verify(r *http.Request) (ClientIdentity, error) {
+	body, err := readRequestBody(r, v.maxBodySize)
+	if err != nil {
+		return ClientIdentity{}, err
+	}
+
+	target := r.Clone(r.Context())
+	target.URL = v.targetURL(r)
+	target.Body = io.NopCloser(bytes.NewReader(body))
+
+	opts := httpsignatureutils.NewValidationOptions(target, r.Header, nil)
+	if v.validation != nil {
+		v.validation(opts)
+	}
+
+	keyID, err := signatureKeyID(r.Header, opts.Label)
+	if err != nil {
+		return ClientIdentity{}, err
+	}
+
+	identity, err := v.resolveKey(r, body, keyID)
+	if err != nil {
+		return ClientIdentity{}, err
+	}
+
+	opts.PublicKey = identity.Key
+	if err := httpsignatureutils.ValidateSignature(opts); err != nil {
+		return ClientIdentity{}, err
+	}
+	return identity, nil
+}
+
+// VerifyResponse checks the signature of resp, which must be made with a key
+// published in the JWKS of walletAddress. The body of resp is left readable.
+func (v *SignatureVerifier) VerifyResponse(resp *http.Response, walletAddress string) error {
+	opts := httpsignatureutils.NewResponseValidationOptions(resp, nil)
+	if v.validation != nil {
+		v.validation(opts)
+	}
+
+	keyID, err := signatureKeyID(resp.Header, opts.Label)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if resp.Request != nil {
+		ctx = resp.Request.Context()
+	}
+	opts.PublicKey, err = v.clientKey(ctx, walletAddress, keyID)
+	if err != nil {
+		return err
+	}
+	return httpsignatureutils.ValidateSignature(opts)
+}
+
+func readRequestBody(r *http.Request, maxSize int64) ([]byte, error) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, nil
+	}
+	if r.ContentLength > maxSize {
+		return nil, ErrRequestBodyTooLarge
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	if int64(len(body)) > maxSize {
+		return nil, ErrRequestBodyTooLarge
+	}
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// targetURL rebuilds the absolute URL the client signed, since server-side
+// requests only carry the path.
+func (v *SignatureVerifier) targetURL(r *http.Request) *url.URL {
+	u := *r.URL
+	switch {
+	case v.baseURL != nil:
+		u.Scheme, u.Host = v.baseURL.Scheme, v.baseURL.Host
+	case r.TLS != nil:
+		u.Scheme, u.Host = "https", r.Host
+	default:
+		u.Scheme, u.Host = "http", r.Host
+	}
+	return &u
+}
+
+func signatureKeyID(headers http.Header, label string) (string, error) {
+	sigs, err := httpsignatureutils.ParseSignatures(
+		strings.Join(headers.Values("Signature-Input"), ", "),
+		strings.Join(headers.Values("Signature"), ", "),
+	)
+	if err != nil {
+		return "", err
+	}
+	for _, sig := range sigs {
+		if label != "" && sig.Label != label {
+			continue
+		}
+		keyID, ok := sig.Params.KeyID()
+		if !ok {
+			return "", fmt.Errorf("%w: missing keyid parameter", httpsignatureutils.ErrInvalidSignature)
+		}
+		return keyID, nil
+	}
+	return "", fmt.Errorf("%w: no signature labeled %q", httpsignatureutils.ErrMissingSignatureInput, label)
+}
+
+// resolveKey finds the public key for keyID. The client field of a grant
+// request body, a directed identity JWK or a wallet address, is only trusted
+// for requests without a GNAP access token. Requests with a token belong to
+// the client their grant was issued to, which only the ClientResolver knows.
+func (v *SignatureVerifier) resolveKey(r *http.Request, body []byte, keyID string) (ClientIdentity, error) {
+	var walletAddress string
+	if _, hasToken := strings.CutPrefix(r.Header.Get("Authorization"), "GNAP "); !hasToken {
+		var jwk *as.JsonWebKey
+		var err error
+		walletAddress, jwk, err = grantRequestClient(body)
+		if err != nil {
+			return ClientIdentity{}, err
+		}
+
+		if jwk != nil {
+			if jwk.Kid != keyID {
+				return ClientIdentity{}, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+			}
+			key, err := httpsignatureutils.PublicKeyFromAuthServerJWK(*jwk)
+			if err != nil {
+				return ClientIdentity{}, err
+			}
+			return ClientIdentity{KeyID: keyID, Key: key}, nil
+		}
+	}
+
+	if walletAddress == "" && v.resolver != nil {
+		var err error
+		walletAddress, err = v.resolver(r, keyID)
+		if err != nil {
+			return ClientIdentity{}, fmt.Errorf("%w: %w", ErrUnknownClient, err)
+		}
+	}
+	if walletAddress == "" {
+		return ClientIdentity{}, ErrUnknownClient
+	}
+
+	key, err := v.clientKey(r.Context(), walletAddress, keyID)
+	if err != nil {
+		return ClientIdentity{}, err
+	}
+	return ClientIdentity{WalletAddress: walletAddress, KeyID: keyID, Key: key}, nil
+}
+
+// grantRequestClient returns the client of a grant request body, if body is
+// one.
+func grantRequestClient(body []byte) (string, *as.JsonWebKey, error) {
+	if len(body) == 0 {
+		return "", nil, nil
+	}
+	var request struct {
+		Client *as.Client `json:"client"`
+	}
+	if err := json.Unmarshal(body, &request); err != nil || request.Client == nil {
+		return "", nil, nil
+	}
+
+	if s, err := request.Client.AsClientWalletAddressString(); err == nil {
+		return s, nil, nil
+	}
+	if wa, err := request.Client.AsClientWalletAddress(); err == nil && wa.WalletAddress != "" {
+		return wa.WalletAddress, nil, nil
+	}
+	if di, err := request.Client.AsClientDirectedIdentity(); err == nil && di.Jwk.X != "" {
+		return "", &di.Jwk, nil
+	}
+	return "", nil, fmt.Errorf("%w: unsupported client field", ErrUnknownClient)
+}
+
+// clientKey returns the key with keyID from the wallet address' JWKS. A
+// cached JWKS without the key is refetched, since the client may have rotated
+// its keys. A JWKS is only cached once it has resolved a key, so unknown
+// wallet addresses don't take up the cache.
+func (v *SignatureVerifier) clientKey(ctx context.Context, walletAddress string, keyID string) (crypto.PublicKey, error) {
+	now := v.now()
+	cached, ok := v.cachedKeys(walletAddress, now)
+	if ok {
+		if jwk, found := findJWK(cached.keys, keyID); found {
+			return publicKeyFromJWK(jwk)
+		}
+		if now.Sub(cached.fetchedAt) < minKeyRefresh {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+		}
+	}
+
+	jwks, err := v.walletAddress.GetKeys(ctx, WalletAddressGetKeysParams{URL: walletAddress})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch client keys: %w", err)
+	}
+	var keys []was.JsonWebKey
+	if jwks.Keys != nil {
+		keys = *jwks.Keys
+	}
+
+	jwk, found := findJWK(keys, keyID)
+	if !found {
+		// Refresh a known client's keys so the unknown key ID isn't
+		// refetched again before minKeyRefresh.
+		if ok {
+			v.cacheKeys(walletAddress, keys, now)
+		}
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	key, err := publicKeyFromJWK(jwk)
+	if err != nil {
+		return nil, err
+	}
+	v.cacheKeys(walletAddress, keys, now)
+	return key, nil
+}
+
+// cachedKeys returns the cached JWKS of walletAddress, evicting those older
+// than the TTL.
+func (v *SignatureVerifier) cachedKeys(walletAddress string, now time.Time) (cachedKeySet, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.evictExpired(now)
+	el, ok := v.cache[walletAddress]
+	if !ok {
+		return cachedKeySet{}, false
+	}
+	return *el.Value.(*cachedKeySet), true
+}
+
+func (v *SignatureVerifier) cacheKeys(walletAddress string, keys []was.JsonWebKey, now time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if el, ok := v.cache[walletAddress]; ok {
+		v.removeCached(el)
+	}
+	v.evictExpired(now)
+	for v.order.Len() >= v.cacheSize {
+		v.removeCached(v.order.Back())
+	}
+	v.cache[walletAddress] = v.order.PushFront(&cachedKeySet{walletAddress: walletAddress, keys: keys, fetchedAt: now})
+}
+
+// evictExpired drops the JWKS fetched longer than the TTL ago, which are at
+// the back of the cache.
+func (v *SignatureVerifier) evictExpired(now time.Time) {
+	for el := v.order.Back(); el != nil && now.Sub(el.Value.(*cachedKeySet).fetchedAt) >= v.ttl; el = v.order.Back() {
+		v.removeCached(el)
+	}
+}
+
+func (v *SignatureVerifier) removeCached(el *list.Element) {
+	v.order.Remove(el)
+	delete(v.cache, el.Value.(*cachedKeySet).walletAddress)
+}
+
+// publicKeyFromJWK returns the public key of jwk, checking that a registered
+// signature algorithm can verify with it.
+func publicKeyFromJWK(jwk was.JsonWebKey) (crypto.PublicKey, error) {
+	key, err := httpsignatureutils.PublicKeyFromJWK(jwk)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := httpsignatureutils.AlgorithmForKey(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func findJWK(keys []was.JsonWebKey, keyID string) (was.JsonWebKey, bool) {
+	for _, key := range keys {
+		if key.Kid == keyID {
+			return key, true
+		}
+	}
+	return was.JsonWebKey{}, false
+}
@@ -0,0 +1,274 @@
+package openpayments_test
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	openpayments "github.com/interledger/open-payments-go"
+	was "github.com/interledger/open-payments-go/generated/walletaddressserver"
+	"github.com/interledger/open-payments-go/httpsignatureutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newVerifierServer(t *testing.T, opts ...openpayments.SignatureVerifierOption) (*httptest.Server, *int32, chan openpayments.ClientIdentity) {
+	t.Helper()
+	key, err := httpsignatureutils.LoadKey(pk)
+	assert.NoError(t, err)
+	pub := key.Public().(ed25519.PublicKey)
+
+	var jwksFetches int32
+	identities := make(chan openpayments.ClientIdentity, 10)
+	protected := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := openpayments.ClientIdentityFromContext(r.Context())
+		assert.True(t, ok)
+		body, _ := io.ReadAll(r.Body)
+		assert.NotEmpty(t, body)
+		identities <- identity
+		w.WriteHeader(http.StatusOK)
+	})
+
+	mux := http.NewServeMux()
+	jwks := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&jwksFetches, 1)
+		_ = json.NewEncoder(w).Encode(was.JsonWebKeySet{Keys: &[]was.JsonWebKey{{
+			Alg: was.EdDSA, Crv: was.Ed25519, Kty: was.OKP, Kid: keyID,
+			X: base64.RawURLEncoding.EncodeToString(pub),
+		}}})
+	}
+	mux.HandleFunc("/alice/jwks.json", jwks)
+	mux.HandleFunc("/bob/jwks.json", jwks)
+
+	server := httptest.NewServer(mux)
+	verifier := openpayments.NewSignatureVerifier(openpayments.NewClient().WalletAddress, opts...)
+	mux.Handle("/", verifier.Middleware(protected))
+	return server, &jwksFetches, identities
+}
+
+func TestSignatureVerifier_GrantRequest(t *testing.T) {
+	server, fetches, identities := newVerifierServer(t)
+	defer server.Close()
+
+	client, err := openpayments.NewAuthenticatedClient(server.URL+"/alice", pk, keyID)
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		body := `{"client":{"walletAddress":"` + server.URL + `/alice"},"access_token":{"access":[]}}`
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/", strings.NewReader(body))
+		resp, err := client.DoSigned(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp.Body.Close()
+
+		identity := <-identities
+		assert.Equal(t, server.URL+"/alice", identity.WalletAddress)
+		assert.Equal(t, keyID, identity.KeyID)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(fetches), "JWKS should be cached")
+}
+
+func TestSignatureVerifier_KeyCache(t *testing.T) {
+	server, fetches, _ := newVerifierServer(t, openpayments.WithKeyCacheSize(1))
+	defer server.Close()
+
+	send := func(client string, kid string) int {
+		signer, err := openpayments.NewAuthenticatedClient(server.URL+"/"+client, pk, kid)
+		assert.NoError(t, err)
+		body := `{"client":"` + server.URL + "/" + client + `","access_token":{"access":[]}}`
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/", strings.NewReader(body))
+		resp, err := signer.DoSigned(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// A lookup that doesn't resolve the key isn't cached.
+	assert.Equal(t, http.StatusUnauthorized, send("alice", "other-key"))
+	assert.Equal(t, http.StatusOK, send("alice", keyID))
+	assert.Equal(t, int32(2), atomic.LoadInt32(fetches))
+
+	// Caching bob's keys evicts alice's.
+	assert.Equal(t, http.StatusOK, send("bob", keyID))
+	assert.Equal(t, http.StatusOK, send("bob", keyID))
+	assert.Equal(t, http.StatusOK, send("alice", keyID))
+	assert.Equal(t, int32(4), atomic.LoadInt32(fetches))
+}
+
+func TestSignatureVerifier_ResourceRequest(t *testing.T) {
+	var resolved string
+	server, _, identities := newVerifierServer(t, openpayments.WithClientResolver(func(r *http.Request, kid string) (string, error) {
+		if r.Header.Get("Authorization") != "GNAP "+accessToken {
+			return "", errors.New("unknown token")
+		}
+		return resolved, nil
+	}))
+	defer server.Close()
+	resolved = server.URL + "/alice"
+
+	client, err := openpayments.NewAuthenticatedClient(server.URL+"/alice", pk, keyID)
+	assert.NoError(t, err)
+
+	send := func(token string, body string) int {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/incoming-payments?x=1", strings.NewReader(body))
+		req.Header.Set("Authorization", "GNAP "+token)
+		resp, err := client.DoSigned(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, send(accessToken, `{"walletAddress":"x"}`))
+	identity := <-identities
+	assert.Equal(t, resolved, identity.WalletAddress)
+
+	assert.Equal(t, http.StatusUnauthorized, send("other-token", `{"walletAddress":"x"}`))
+}
+
+func TestSignatureVerifier_Rejects(t *testing.T) {
+	server, _, _ := newVerifierServer(t)
+	defer server.Close()
+
+	grantBody := `{"client":"` + server.URL + `/alice","access_token":{"access":[]}}`
+
+	t.Run("unknown key", func(t *testing.T) {
+		client, err := openpayments.NewAuthenticatedClient(server.URL+"/alice", pk, "other-key")
+		assert.NoError(t, err)
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/", strings.NewReader(grantBody))
+		resp, err := client.DoSigned(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		client, err := openpayments.NewAuthenticatedClient(server.URL+"/alice", pk, keyID, openpayments.WithPostSignHook(func(req *http.Request) {
+			tampered := strings.Replace(grantBody, "[]", "[{}]", 1)
+			req.Body = io.NopCloser(strings.NewReader(tampered))
+			req.ContentLength = int64(len(tampered))
+		}))
+		assert.NoError(t, err)
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/", strings.NewReader(grantBody))
+		resp, err := client.DoSigned(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("client in body of token request", func(t *testing.T) {
+		client, err := openpayments.NewAuthenticatedClient(server.URL+"/alice", pk, keyID)
+		assert.NoError(t, err)
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/incoming-payments", strings.NewReader(grantBody))
+		req.Header.Set("Authorization", "GNAP "+accessToken)
+		resp, err := client.DoSigned(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+
+	t.Run("unknown client", func(t *testing.T) {
+		client, err := openpayments.NewAuthenticatedClient(server.URL+"/alice", pk, keyID)
+		assert.NoError(t, err)
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/incoming-payments", strings.NewReader(`{}`))
+		resp, err := client.DoSigned(req)
+		assert.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	})
+}
+
+func TestSignatureVerifier_MaxBodySize(t *testing.T) {
+	var verifyErr error
+	server, _, _ := newVerifierServer(t,
+		openpayments.WithMaxBodySize(16),
+		openpayments.WithVerificationErrorHandler(func(w http.ResponseWriter, r *http.Request, err error) {
+			verifyErr = err
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+	defer server.Close()
+
+	client, err := openpayments.NewAuthenticatedClient(server.URL+"/alice", pk, keyID)
+	assert.NoError(t, err)
+	body := `{"client":"` + server.URL + `/alice","access_token":{"access":[]}}`
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/", strings.NewReader(body))
+	resp, err := client.DoSigned(req)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.ErrorIs(t, verifyErr, openpayments.ErrRequestBodyTooLarge)
+}
+
+func TestAuthenticatedClient_ResponseVerification(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	signingKeyID := "server-key"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jwks.json", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(was.JsonWebKeySet{Keys: &[]was.JsonWebKey{{
+			Alg: was.EdDSA, Crv: was.Ed25519, Kty: was.OKP, Kid: "server-key",
+			X: base64.RawURLEncoding.EncodeToString(pub),
+		}}})
+	})
+	mux.HandleFunc("/incoming-payments/1", func(w http.ResponseWriter, r *http.Request) {
+		body := []byte(`{"id":"1"}`)
+		content := httpsignatureutils.CreateContentHeaders(body)
+		w.Header().Set("Content-Digest", content.ContentDigest)
+		w.Header().Set("Content-Type", content.ContentType)
+
+		req := r.Clone(r.Context())
+		req.URL.Scheme, req.URL.Host = "http", r.Host
+		headers, err := httpsignatureutils.CreateResponseSignatureHeaders(httpsignatureutils.ResponseSignOptions{
+			Response:   &http.Response{StatusCode: http.StatusOK, Header: w.Header(), Request: req},
+			PrivateKey: priv,
+			KeyID:      signingKeyID,
+		})
+		assert.NoError(t, err)
+		w.Header().Set("Signature", headers.SignatureHeader)
+		w.Header().Set("Signature-Input", headers.SignatureInput)
+		_, _ = w.Write(body)
+	})
+	mux.HandleFunc("/incoming-payments/unsigned", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"id":"unsigned"}`))
+	})
+	mux.HandleFunc("/incoming-payments/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Signature", "sig1=:AAAA:")
+		http.Error(w, `{"error":{"code":"not_found"}}`, http.StatusNotFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client, err := openpayments.NewAuthenticatedClient(walletAddress, pk, keyID, openpayments.WithResponseVerification(nil))
+	assert.NoError(t, err)
+
+	get := func(id string) (*http.Response, error) {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/incoming-payments/"+id, nil)
+		req.Header.Set("Authorization", "GNAP "+accessToken)
+		return client.DoSigned(req)
+	}
+
+	resp, err := get("1")
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, `{"id":"1"}`, string(body))
+
+	resp, err = get("unsigned")
+	assert.NoError(t, err)
+	resp.Body.Close()
+
+	resp, err = get("missing")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	signingKeyID = "unpublished-key"
+	_, err = get("1")
+	assert.ErrorIs(t, err, openpayments.ErrUnknownKey)
+}
@@ -0,0 +1,86 @@
+package openpayments
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/interledger/open-payments-go/httpsignatureutils"
+)
+
+// KeyNotPublishedError is returned by VerifySigningKey when the client's
+// wallet address doesn't publish a key with the signing key's ID.
+type KeyNotPublishedError struct {
+	WalletAddress string
+	KeyID         string
+}
+
+func (e *KeyNotPublishedError) Error() string {
+	return fmt.Sprintf("signing key %s is not published in the JWKS of %s", e.KeyID, e.WalletAddress)
+}
+
+// KeyMismatchError is returned by VerifySigningKey when the key the client's
+// wallet address publishes under the signing key's ID is a different key.
+type KeyMismatchError struct {
+	WalletAddress string
+	KeyID         string
+	PublishedX    string // The x member of the published JWK.
+	LocalX        string // The x member of the signing key's JWK.
+}
+
+func (e *KeyMismatchError) Error() string {
+	return fmt.Sprintf("signing key %s doesn't match the key published by %s: published x=%s, local x=%s",
+		e.KeyID, e.WalletAddress, e.PublishedX, e.LocalX)
+}
+
+// signingKeyCheckTimeout bounds the key fetch of WithSigningKeyCheck.
+const signingKeyCheckTimeout = 10 * time.Second
+
+// WithSigningKeyCheck makes the client constructor call VerifySigningKey and
+// fail if the signing key isn't published by the client's wallet address. Call
+// VerifySigningKey instead to control the request's context.
+func WithSigningKeyCheck() AuthenticatedClientOption {
+	return func(c *AuthenticatedClient) {
+		c.checkSigningKey = true
+	}
+}
+
+// VerifySigningKey checks that the client's wallet address publishes the
+// active signing key, so a wrong key ID or key shows up at startup rather than
+// as an invalid_client error on the first grant request. It returns a
+// *KeyNotPublishedError or *KeyMismatchError if it doesn't.
+func (c *AuthenticatedClient) VerifySigningKey(ctx context.Context) error {
+	signer, err := c.keys.Active()
+	if err != nil {
+		return err
+	}
+	pub, ok := signer.Public().(ed25519.PublicKey)
+	if !ok {
+		return fmt.Errorf("wallet addresses only publish Ed25519 keys, signing key %s is a %T", signer.KeyID(), signer.Public())
+	}
+
+	jwks, err := c.WalletAddress.GetKeys(ctx, WalletAddressGetKeysParams{URL: c.walletAddressUrl})
+	if err != nil {
+		return fmt.Errorf("failed to fetch client keys: %w", err)
+	}
+	if jwks.Keys == nil {
+		return &KeyNotPublishedError{WalletAddress: c.walletAddressUrl, KeyID: signer.KeyID()}
+	}
+	jwk, found := findJWK(*jwks.Keys, signer.KeyID())
+	if !found {
+		return &KeyNotPublishedError{WalletAddress: c.walletAddressUrl, KeyID: signer.KeyID()}
+	}
+
+	published, err := httpsignatureutils.PublicKeyFromJWK(jwk)
+	if err != nil || !published.Equal(pub) {
+		return &KeyMismatchError{
+			WalletAddress: c.walletAddressUrl,
+			KeyID:         signer.KeyID(),
+			PublishedX:    jwk.X,
+			LocalX:        base64.RawURLEncoding.EncodeToString(pub),
+		}
+	}
+	return nil
+}
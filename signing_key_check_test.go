@@ -0,0 +1,56 @@
+package openpayments_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	openpayments "github.com/interledger/open-payments-go"
+	was "github.com/interledger/open-payments-go/generated/walletaddressserver"
+	"github.com/interledger/open-payments-go/httpsignatureutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySigningKey(t *testing.T) {
+	key, err := httpsignatureutils.LoadKey(pk)
+	assert.NoError(t, err)
+	otherKey, _, _ := ed25519.GenerateKey(rand.Reader)
+
+	server := httptest.NewServer(httpsignatureutils.JWKSHandler(func(r *http.Request, walletAddressPath string) (was.JsonWebKeySet, bool) {
+		switch walletAddressPath {
+		case "/alice":
+			return httpsignatureutils.NewJSONWebKeySet(httpsignatureutils.PublicKeyToJWK(key.Public().(ed25519.PublicKey), keyID)), true
+		case "/bob":
+			return httpsignatureutils.NewJSONWebKeySet(httpsignatureutils.PublicKeyToJWK(otherKey, keyID)), true
+		case "/carol":
+			return httpsignatureutils.NewJSONWebKeySet(httpsignatureutils.PublicKeyToJWK(key.Public().(ed25519.PublicKey), "other-key")), true
+		}
+		return was.JsonWebKeySet{}, false
+	}))
+	defer server.Close()
+
+	client, err := openpayments.NewAuthenticatedClient(server.URL+"/alice", pk, keyID)
+	assert.NoError(t, err)
+	assert.NoError(t, client.VerifySigningKey(context.Background()))
+
+	client, err = openpayments.NewAuthenticatedClient(server.URL+"/bob", pk, keyID)
+	assert.NoError(t, err)
+	var mismatch *openpayments.KeyMismatchError
+	assert.True(t, errors.As(client.VerifySigningKey(context.Background()), &mismatch))
+	assert.Equal(t, keyID, mismatch.KeyID)
+
+	client, err = openpayments.NewAuthenticatedClient(server.URL+"/carol", pk, keyID)
+	assert.NoError(t, err)
+	var notPublished *openpayments.KeyNotPublishedError
+	assert.True(t, errors.As(client.VerifySigningKey(context.Background()), &notPublished))
+	assert.Equal(t, server.URL+"/carol", notPublished.WalletAddress)
+
+	_, err = openpayments.NewAuthenticatedClient(server.URL+"/carol", pk, keyID, openpayments.WithSigningKeyCheck())
+	assert.True(t, errors.As(err, &notPublished))
+	_, err = openpayments.NewAuthenticatedClient(server.URL+"/alice", pk, keyID, openpayments.WithSigningKeyCheck())
+	assert.NoError(t, err)
+}
@@ -732,26 +732,15 @@ func TestRevokeToken(t *testing.T) {
 //
 // ==============
 func newIncomingPaymentGrant() (*op.Grant, error) {
-	incomingAccess := as.AccessIncoming{
-		Type: as.IncomingPayment,
+	requestBody, err := op.NewIncomingPaymentGrantRequest(op.IncomingPaymentGrantParams{
 		Actions: []as.AccessIncomingActions{
 			as.AccessIncomingActionsCreate,
 			as.AccessIncomingActionsRead,
 			as.AccessIncomingActionsList,
 			as.AccessIncomingActionsComplete,
 		},
-	}
-	accessItem := as.AccessItem{}
-	if err := accessItem.FromAccessIncoming(incomingAccess); err != nil {
-		return nil, fmt.Errorf("Error creating AccessItem: %w", err)
-	}
-
-	var requestBody as.GrantRequest
-	if err := requestBody.FromGrantRequestWithAccessToken(as.GrantRequestWithAccessToken{
-		AccessToken: as.AccessTokenRequest{
-			Access: []as.AccessItem{accessItem},
-		},
-	}); err != nil {
+	})
+	if err != nil {
 		return nil, fmt.Errorf("Error creating grant request body: %w", err)
 	}
 
@@ -796,25 +785,15 @@ func newIncomingPayment(grant *op.Grant) (*rs.IncomingPaymentWithMethods, error)
 }
 
 func newQuote(incomingPayment *rs.IncomingPaymentWithMethods) (*rs.Quote, error) {
-	quoteAccess := as.AccessQuote{
-		Type: as.Quote,
+	quoteGrantRequestBody, err := op.NewQuoteGrantRequest(op.QuoteGrantParams{
 		Actions: []as.AccessQuoteActions{
 			// TODO: address how these arent scoped to quotes?
 			// anti-corruption layer for the generated types?
 			as.Create,
 			as.Read,
 		},
-	}
-	accessItem := as.AccessItem{}
-	if err := accessItem.FromAccessQuote(quoteAccess); err != nil {
-		return nil, fmt.Errorf("error creating AccessItem for quote: %w", err)
-	}
-	var quoteGrantRequestBody as.GrantRequest
-	if err := quoteGrantRequestBody.FromGrantRequestWithAccessToken(as.GrantRequestWithAccessToken{
-		AccessToken: as.AccessTokenRequest{
-			Access: []as.AccessItem{accessItem},
-		},
-	}); err != nil {
+	})
+	if err != nil {
 		return nil, fmt.Errorf("error creating quote grant request body: %w", err)
 	}
 
@@ -850,30 +829,16 @@ func newQuote(incomingPayment *rs.IncomingPaymentWithMethods) (*rs.Quote, error)
 }
 
 func newOutgoingPaymentGrant() (*op.Grant, error) {
-	outgoingAccess := as.AccessOutgoing{
-		Type: as.OutgoingPayment,
+	grantRequestBody, err := op.NewOutgoingPaymentGrantRequest(op.OutgoingPaymentGrantParams{
 		Actions: []as.AccessOutgoingActions{
 			as.AccessOutgoingActionsCreate,
 			as.AccessOutgoingActionsRead,
 			as.AccessOutgoingActionsList,
 		},
 		Identifier: environment.ResolvedSenderWalletAddressUrl,
-	}
-	accessItem := as.AccessItem{}
-	if err := accessItem.FromAccessOutgoing(outgoingAccess); err != nil {
-		return nil, fmt.Errorf("error creating AccessItem: %w", err)
-	}
-	interact := &as.InteractRequest{
-		Start: []as.InteractRequestStart{as.InteractRequestStartRedirect},
-	}
-
-	var grantRequestBody as.GrantRequest
-	if err := grantRequestBody.FromGrantRequestWithAccessToken(as.GrantRequestWithAccessToken{
-		AccessToken: as.AccessTokenRequest{
-			Access: []as.AccessItem{accessItem},
-		},
-		Interact: interact,
-	}); err != nil {
+		Interact:   op.NewRedirectInteract("", ""),
+	})
+	if err != nil {
 		return nil, fmt.Errorf("error creating grant request body: %w", err)
 	}
 
@@ -12,6 +12,9 @@ import (
 
 type TokenService struct {
 	DoSigned RequestDoer
+	store    GrantStore
+	recovery *tokenRecoverer
+	signers  *tokenSigners
 }
 
 type TokenRotateParams struct {
@@ -56,6 +59,18 @@ func (ts *TokenService) Rotate(ctx context.Context, params TokenRotateParams) (a
 		return as.AccessToken{}, fmt.Errorf("failed to decode response: %w", err)
 	}
 
+	ts.signers.rebind(params.AccessToken, response.AccessToken)
+
+	if ts.recovery != nil {
+		ts.recovery.rotated(params.AccessToken, response.AccessToken)
+	}
+
+	if ts.store != nil {
+		if err := updateStoredAccessToken(ctx, ts.store, params.URL, &response.AccessToken); err != nil {
+			return response.AccessToken, fmt.Errorf("failed to save rotated token: %w", err)
+		}
+	}
+
 	return response.AccessToken, nil
 }
 
@@ -81,5 +96,17 @@ func (ts *TokenService) Revoke(ctx context.Context, params TokenRevokeParams) er
 		return newClientErrorFromResponse(req, resp)
 	}
 
+	ts.signers.unbind(params.AccessToken)
+
+	if ts.recovery != nil {
+		ts.recovery.revoked(params.AccessToken)
+	}
+
+	if ts.store != nil {
+		if err := updateStoredAccessToken(ctx, ts.store, params.URL, nil); err != nil {
+			return fmt.Errorf("failed to update stored grant: %w", err)
+		}
+	}
+
 	return nil
 }
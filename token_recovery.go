@@ -0,0 +1,252 @@
+package openpayments
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	as "github.com/interledger/open-payments-go/generated/authserver"
+)
+
+type TokenRecoveryPath string
+
+const (
+	TokenRecoveryRotated   TokenRecoveryPath = "rotated"   // The access token was rotated.
+	TokenRecoveryRegranted TokenRecoveryPath = "regranted" // The grant was requested again.
+	TokenRecoveryFailed    TokenRecoveryPath = "failed"    // Neither worked; the request fails with both errors.
+)
+
+// TokenRecovery describes how a request rejected with 401 was recovered.
+type TokenRecovery struct {
+	Path          TokenRecoveryPath
+	PreviousToken string
+	Grant         Grant // The grant holding the new access token. Empty if recovery failed.
+	Err           error // Set if recovery failed.
+}
+
+// WithTokenRecovery retries resource server requests rejected with an
+// invalid_token error once with a new access token. The token is first rotated
+// with TokenService.Rotate and, if that fails and the grant was
+// non-interactive, the grant is requested again with the parameters originally
+// passed to GrantService.Request. Other 401 errors, such as invalid_client,
+// are returned as they are.
+//
+// Only tokens from grants obtained through this client are recovered.
+// onRecover, if not nil, is called after each attempt so the caller can pick
+// up the new access token. A RotatingTokenSource created with the client's
+// TokenService picks it up on its next use.
+func WithTokenRecovery(onRecover func(TokenRecovery)) AuthenticatedClientOption {
+	return func(c *AuthenticatedClient) {
+		c.recovery = &tokenRecoverer{
+			onRecover: onRecover,
+			grants:    make(map[string]*recoverableGrant),
+		}
+	}
+}
+
+type recoverableGrant struct {
+	recovering sync.Mutex // Serializes recoveries of the same grant.
+	grant      Grant
+	request    *GrantRequestParams // Nil unless the grant can be requested again.
+	superseded string              // The access token grant.AccessToken replaced, if any.
+}
+
+type tokenRecoverer struct {
+	client    *AuthenticatedClient
+	onRecover func(TokenRecovery)
+
+	mu     sync.Mutex
+	grants map[string]*recoverableGrant // By access token value, including each grant's superseded token.
+}
+
+// track records a grant returned by the grant service.
+func (r *tokenRecoverer) track(grant Grant, request *GrantRequestParams) {
+	if grant.AccessToken == nil {
+		return
+	}
+	if grant.IsInteractive() {
+		request = nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.grants[grant.AccessToken.Value] = &recoverableGrant{grant: grant, request: request}
+}
+
+// rotated records a token rotated with the token service.
+func (r *tokenRecoverer) rotated(previousToken string, token as.AccessToken) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.grants[previousToken]
+	if !ok {
+		return
+	}
+	entry.grant.AccessToken = &token
+	r.supersede(entry, previousToken)
+}
+
+// revoked forgets a token revoked with the token service.
+func (r *tokenRecoverer) revoked(token string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry, ok := r.grants[token]; ok && entry.superseded != "" {
+		delete(r.grants, entry.superseded)
+	}
+	delete(r.grants, token)
+}
+
+// supersede maps the grant's new access token to entry. The previous token
+// stays mapped so requests still using it are swapped for the new one, but
+// older tokens are forgotten. r.mu must be held.
+func (r *tokenRecoverer) supersede(entry *recoverableGrant, previousToken string) {
+	if entry.superseded != "" {
+		delete(r.grants, entry.superseded)
+	}
+	entry.superseded = previousToken
+	r.grants[entry.grant.AccessToken.Value] = entry
+}
+
+// current returns the latest access token of the grant token belongs to.
+func (r *tokenRecoverer) current(token string) (as.AccessToken, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.grants[token]
+	if !ok || entry.grant.AccessToken == nil {
+		return as.AccessToken{}, false
+	}
+	return *entry.grant.AccessToken, true
+}
+
+func (r *tokenRecoverer) lookup(token string) (*recoverableGrant, Grant) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.grants[token]
+	if !ok {
+		return nil, Grant{}
+	}
+	return entry, entry.grant
+}
+
+func (r *tokenRecoverer) replace(entry *recoverableGrant, grant Grant) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previousToken := entry.grant.AccessToken.Value
+	entry.grant = grant
+	r.supersede(entry, previousToken)
+}
+
+func (r *tokenRecoverer) DoSigned(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	// Look the grant up before sending, since a concurrent recovery may
+	// replace token while the request is in flight.
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "GNAP ")
+	var entry *recoverableGrant
+	if ok {
+		entry, _ = r.lookup(token)
+	}
+
+	resp, err := r.client.DoSigned(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || entry == nil {
+		return resp, err
+	}
+	// A new token can't fix other errors, such as invalid_client
+	clientErr := peekClientError(req, resp)
+	if !errors.Is(clientErr, ErrInvalidToken) &&
+		!strings.Contains(resp.Header.Get("WWW-Authenticate"), `error="invalid_token"`) {
+		return resp, nil
+	}
+
+	newToken, err := r.recover(req.Context(), entry, token)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%w (access token recovery failed: %w)", clientErr, err)
+	}
+
+	retry := req.Clone(req.Context())
+	if body != nil {
+		retry.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	retry.Header.Set("Authorization", fmt.Sprintf("GNAP %s", newToken))
+	return r.client.DoSigned(retry)
+}
+
+// recover returns a usable access token for the grant token belongs to.
+func (r *tokenRecoverer) recover(ctx context.Context, entry *recoverableGrant, token string) (string, error) {
+	entry.recovering.Lock()
+	defer entry.recovering.Unlock()
+
+	r.mu.Lock()
+	grant := entry.grant
+	r.mu.Unlock()
+	if grant.AccessToken == nil {
+		return "", fmt.Errorf("grant has no access token")
+	}
+	// another request already recovered this grant
+	if grant.AccessToken.Value != token {
+		return grant.AccessToken.Value, nil
+	}
+
+	rotated, rotateErr := r.client.Token.Rotate(ctx, TokenRotateParams{
+		URL:         grant.AccessToken.Manage,
+		AccessToken: grant.AccessToken.Value,
+	})
+	if rotateErr == nil {
+		// Token.Rotate has already updated the entry.
+		grant.AccessToken = &rotated
+		r.report(TokenRecovery{Path: TokenRecoveryRotated, PreviousToken: token, Grant: grant})
+		return rotated.Value, nil
+	}
+
+	if entry.request == nil {
+		err := fmt.Errorf("failed to rotate access token: %w", rotateErr)
+		r.report(TokenRecovery{Path: TokenRecoveryFailed, PreviousToken: token, Err: err})
+		return "", err
+	}
+
+	regranted, err := r.client.Grant.Request(ctx, *entry.request)
+	if err == nil && regranted.AccessToken == nil {
+		err = fmt.Errorf("grant request returned no access token")
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to rotate access token (%v) or request grant: %w", rotateErr, err)
+		r.report(TokenRecovery{Path: TokenRecoveryFailed, PreviousToken: token, Err: err})
+		return "", err
+	}
+
+	r.replace(entry, regranted)
+	r.report(TokenRecovery{Path: TokenRecoveryRegranted, PreviousToken: token, Grant: regranted})
+	return regranted.AccessToken.Value, nil
+}
+
+// peekClientError returns the error resp holds, leaving its body readable.
+func peekClientError(req *http.Request, resp *http.Response) *OpenPaymentsClientError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	rest := resp.Body
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	clientErr := newClientErrorFromResponse(req, resp)
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), rest), rest}
+	return clientErr
+}
+
+func (r *tokenRecoverer) report(recovery TokenRecovery) {
+	if r.onRecover != nil {
+		r.onRecover(recovery)
+	}
+}
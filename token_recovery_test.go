@@ -0,0 +1,223 @@
+package openpayments_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	openpayments "github.com/interledger/open-payments-go"
+	as "github.com/interledger/open-payments-go/generated/authserver"
+	"github.com/stretchr/testify/assert"
+)
+
+type recoveryServer struct {
+	*httptest.Server
+	validToken   string
+	rotateFails  bool
+	grantFails   bool
+	rejectCode   string
+	grants       int
+	rotations    int
+	resourceHits []string
+}
+
+func newRecoveryServer(t *testing.T, rotateFails bool) *recoveryServer {
+	t.Helper()
+	s := &recoveryServer{validToken: "token-0", rotateFails: rotateFails, rejectCode: "invalid_token"}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/":
+			if s.grantFails {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			token := fmt.Sprintf("grant-token-%d", s.grants)
+			s.grants++
+			if s.grants > 1 {
+				s.validToken = token
+			}
+			_ = json.NewEncoder(w).Encode(openpayments.Grant{
+				AccessToken: &as.AccessToken{Value: token, Manage: s.URL + "/token/" + token},
+				Continue:    as.Continue{Uri: s.URL + "/continue/1"},
+			})
+		case strings.HasPrefix(r.URL.Path, "/token/"):
+			s.rotations++
+			if s.rotateFails {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			s.validToken = fmt.Sprintf("rotated-token-%d", s.rotations)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"access_token": as.AccessToken{Value: s.validToken, Manage: s.URL + "/token/" + s.validToken},
+			})
+		default:
+			s.resourceHits = append(s.resourceHits, r.Header.Get("Authorization"))
+			body, _ := json.Marshal(map[string]any{})
+			if r.Header.Get("Authorization") != "GNAP "+s.validToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = fmt.Fprintf(w, `{"error":{"code":%q,"description":"Unauthorized"}}`, s.rejectCode)
+				return
+			}
+			if r.Header.Get("Content-Digest") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(body)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func requestRecoverableGrant(t *testing.T, s *recoveryServer) (*openpayments.AuthenticatedClient, openpayments.Grant, *[]openpayments.TokenRecovery) {
+	t.Helper()
+	var recoveries []openpayments.TokenRecovery
+	client, err := openpayments.NewAuthenticatedClient(walletAddress, pk, keyID,
+		openpayments.WithHTTPClientAuthed(s.Client()),
+		openpayments.WithTokenRecovery(func(recovery openpayments.TokenRecovery) {
+			recoveries = append(recoveries, recovery)
+		}),
+	)
+	assert.NoError(t, err)
+
+	body, err := openpayments.NewIncomingPaymentGrantRequest(openpayments.IncomingPaymentGrantParams{
+		Actions: []as.AccessIncomingActions{as.AccessIncomingActionsCreate},
+	})
+	assert.NoError(t, err)
+
+	grant, err := client.Grant.Request(context.Background(), openpayments.GrantRequestParams{URL: s.URL + "/", RequestBody: body})
+	assert.NoError(t, err)
+	return client, grant, &recoveries
+}
+
+func TestTokenRecovery_Rotates(t *testing.T) {
+	s := newRecoveryServer(t, false)
+	client, grant, recoveries := requestRecoverableGrant(t, s)
+
+	for i := 0; i < 2; i++ {
+		_, err := client.IncomingPayment.Create(context.Background(), openpayments.IncomingPaymentCreateParams{
+			BaseURL:     s.URL,
+			AccessToken: grant.AccessToken.Value,
+		})
+		assert.NoError(t, err)
+	}
+
+	// the stale token is swapped for the rotated one without rotating again
+	assert.Equal(t, 1, s.rotations)
+	assert.Equal(t, []string{"GNAP grant-token-0", "GNAP rotated-token-1", "GNAP grant-token-0", "GNAP rotated-token-1"}, s.resourceHits)
+	assert.Len(t, *recoveries, 1)
+	assert.Equal(t, openpayments.TokenRecoveryRotated, (*recoveries)[0].Path)
+	assert.Equal(t, "grant-token-0", (*recoveries)[0].PreviousToken)
+	assert.Equal(t, "rotated-token-1", (*recoveries)[0].Grant.AccessToken.Value)
+}
+
+func TestTokenRecovery_Regrants(t *testing.T) {
+	s := newRecoveryServer(t, true)
+	client, grant, recoveries := requestRecoverableGrant(t, s)
+
+	_, err := client.IncomingPayment.Create(context.Background(), openpayments.IncomingPaymentCreateParams{
+		BaseURL:     s.URL,
+		AccessToken: grant.AccessToken.Value,
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, s.grants)
+	assert.Len(t, *recoveries, 1)
+	assert.Equal(t, openpayments.TokenRecoveryRegranted, (*recoveries)[0].Path)
+	assert.Equal(t, "grant-token-1", (*recoveries)[0].Grant.AccessToken.Value)
+}
+
+func TestTokenRecovery_UnknownTokenNotRecovered(t *testing.T) {
+	s := newRecoveryServer(t, false)
+	client, _, recoveries := requestRecoverableGrant(t, s)
+
+	_, err := client.IncomingPayment.Create(context.Background(), openpayments.IncomingPaymentCreateParams{
+		BaseURL:     s.URL,
+		AccessToken: "unknown-token",
+	})
+
+	var clientErr *openpayments.OpenPaymentsClientError
+	assert.ErrorAs(t, err, &clientErr)
+	assert.Equal(t, http.StatusUnauthorized, clientErr.Status)
+	assert.Equal(t, 0, s.rotations)
+	assert.Empty(t, *recoveries)
+}
+
+func TestTokenRecovery_ForgetsOlderTokens(t *testing.T) {
+	s := newRecoveryServer(t, false)
+	client, grant, _ := requestRecoverableGrant(t, s)
+
+	create := func(token string) error {
+		_, err := client.IncomingPayment.Create(context.Background(), openpayments.IncomingPaymentCreateParams{
+			BaseURL:     s.URL,
+			AccessToken: token,
+		})
+		return err
+	}
+
+	assert.NoError(t, create(grant.AccessToken.Value))
+	s.validToken = "expired"
+	assert.NoError(t, create("rotated-token-1"))
+	assert.Equal(t, 2, s.rotations)
+
+	// only the most recently superseded token is still swapped
+	assert.NoError(t, create("rotated-token-1"))
+	var clientErr *openpayments.OpenPaymentsClientError
+	assert.ErrorAs(t, create(grant.AccessToken.Value), &clientErr)
+	assert.Equal(t, http.StatusUnauthorized, clientErr.Status)
+	assert.Equal(t, 2, s.rotations)
+}
+
+func TestTokenRecovery_OnlyInvalidToken(t *testing.T) {
+	s := newRecoveryServer(t, false)
+	client, grant, recoveries := requestRecoverableGrant(t, s)
+	s.rejectCode = "invalid_client"
+
+	_, err := client.IncomingPayment.Create(context.Background(), openpayments.IncomingPaymentCreateParams{
+		BaseURL:     s.URL,
+		AccessToken: grant.AccessToken.Value,
+	})
+
+	assert.ErrorIs(t, err, openpayments.ErrInvalidClient)
+	assert.Equal(t, 0, s.rotations)
+	assert.Equal(t, 1, s.grants)
+	assert.Empty(t, *recoveries)
+}
+
+func TestTokenRecovery_ReturnsRecoveryError(t *testing.T) {
+	s := newRecoveryServer(t, true)
+	client, grant, recoveries := requestRecoverableGrant(t, s)
+	s.grantFails = true
+
+	_, err := client.IncomingPayment.Create(context.Background(), openpayments.IncomingPaymentCreateParams{
+		BaseURL:     s.URL,
+		AccessToken: grant.AccessToken.Value,
+	})
+
+	assert.ErrorIs(t, err, openpayments.ErrInvalidToken)
+	assert.ErrorContains(t, err, "access token recovery failed")
+	assert.Len(t, *recoveries, 1)
+	assert.Equal(t, openpayments.TokenRecoveryFailed, (*recoveries)[0].Path)
+}
+
+func TestTokenRecovery_UpdatesTokenSource(t *testing.T) {
+	s := newRecoveryServer(t, false)
+	client, grant, _ := requestRecoverableGrant(t, s)
+	source := openpayments.NewRotatingTokenSource(client.Token, *grant.AccessToken)
+
+	_, err := client.IncomingPayment.Create(context.Background(), openpayments.IncomingPaymentCreateParams{
+		BaseURL:     s.URL,
+		TokenSource: source,
+	})
+	assert.NoError(t, err)
+
+	token, err := source.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "rotated-token-1", token)
+	assert.Equal(t, 1, s.rotations)
+}
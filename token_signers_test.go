@@ -0,0 +1,59 @@
+package openpayments
+
+import (
+	"testing"
+	"time"
+
+	as "github.com/interledger/open-payments-go/generated/authserver"
+	"github.com/interledger/open-payments-go/httpsignatureutils/signertest"
+)
+
+func TestTokenSigners_UnbindsReplacedTokens(t *testing.T) {
+	ts := newTokenSigners()
+	signer := signertest.New("ephemeral")
+
+	grant := Grant{Continue: as.Continue{AccessToken: struct {
+		Value string `json:"value"`
+	}{Value: "continue-1"}}}
+	ts.bindGrant(signer, grant)
+
+	grant.Continue.AccessToken.Value = "continue-2"
+	grant.AccessToken = &as.AccessToken{Value: "access-1"}
+	ts.continued("continue-1", grant)
+	ts.rebind("access-1", as.AccessToken{Value: "access-2"})
+
+	if ts.lookup("continue-1") != nil || ts.lookup("access-1") != nil {
+		t.Errorf("expected replaced tokens to be unbound")
+	}
+	if ts.lookup("continue-2") != signer || ts.lookup("access-2") != signer {
+		t.Errorf("expected current tokens to stay bound")
+	}
+	if len(ts.signers) != 2 {
+		t.Errorf("expected 2 bound tokens, got %d", len(ts.signers))
+	}
+}
+
+func TestTokenSigners_Expire(t *testing.T) {
+	ts := newTokenSigners()
+	now := time.Now()
+	ts.now = func() time.Time { return now }
+	signer := signertest.New("ephemeral")
+
+	expiresIn := 60
+	ts.bindGrant(signer, Grant{AccessToken: &as.AccessToken{Value: "access-1", ExpiresIn: &expiresIn}})
+	ts.bindGrant(signer, Grant{AccessToken: &as.AccessToken{Value: "access-2"}})
+
+	now = now.Add(tokenSignerTTL + time.Second)
+	if ts.lookup("access-1") != signer {
+		t.Errorf("expected an expired token to stay bound for rotation")
+	}
+	if ts.lookup("access-2") != nil {
+		t.Errorf("expected a token without expiry to be unbound after the TTL")
+	}
+
+	now = now.Add(time.Minute)
+	ts.bindGrant(signer, Grant{AccessToken: &as.AccessToken{Value: "access-3"}})
+	if len(ts.signers) != 1 {
+		t.Errorf("expected expired bindings to be swept, got %d", len(ts.signers))
+	}
+}
@@ -0,0 +1,151 @@
+package openpayments
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	as "github.com/interledger/open-payments-go/generated/authserver"
+)
+
+// TokenSource supplies the access token for resource server requests. The
+// resource service params accept one in place of a static AccessToken.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// StaticTokenSource always returns the same access token.
+type StaticTokenSource string
+
+func (s StaticTokenSource) Token(_ context.Context) (string, error) {
+	return string(s), nil
+}
+
+// DefaultTokenExpiryDelta is how long before expiry a RotatingTokenSource
+// rotates its token.
+const DefaultTokenExpiryDelta = 10 * time.Second
+
+// RotatingTokenSource wraps a grant's access token and rotates it with
+// TokenService.Rotate shortly before it expires. It is safe for concurrent use,
+// and concurrent callers share a single rotation. With WithTokenRecovery, it
+// also picks up tokens the client recovered.
+type RotatingTokenSource struct {
+	tokens      *TokenService
+	expiryDelta time.Duration
+	onRotate    func(as.AccessToken)
+	now         func() time.Time
+
+	mu     sync.Mutex
+	token  as.AccessToken
+	expiry time.Time // Zero if the token doesn't expire.
+}
+
+// RotatingTokenSourceOption is used to configure optional behavior for the token source.
+type RotatingTokenSourceOption func(*RotatingTokenSource)
+
+// WithTokenExpiryDelta sets how long before expiry the token is rotated.
+func WithTokenExpiryDelta(delta time.Duration) RotatingTokenSourceOption {
+	return func(s *RotatingTokenSource) {
+		s.expiryDelta = delta
+	}
+}
+
+// WithTokenRotateHook sets a function called with each newly rotated token,
+// e.g. to persist it.
+func WithTokenRotateHook(onRotate func(as.AccessToken)) RotatingTokenSourceOption {
+	return func(s *RotatingTokenSource) {
+		s.onRotate = onRotate
+	}
+}
+
+func NewRotatingTokenSource(tokens *TokenService, token as.AccessToken, opts ...RotatingTokenSourceOption) *RotatingTokenSource {
+	s := &RotatingTokenSource{
+		tokens:      tokens,
+		expiryDelta: DefaultTokenExpiryDelta,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.set(token)
+	return s
+}
+
+func (s *RotatingTokenSource) set(token as.AccessToken) {
+	s.token = token
+	s.expiry = time.Time{}
+	if token.ExpiresIn != nil {
+		s.expiry = s.now().Add(time.Duration(*token.ExpiresIn) * time.Second)
+	}
+}
+
+func (s *RotatingTokenSource) expired() bool {
+	return !s.expiry.IsZero() && !s.now().Add(s.expiryDelta).Before(s.expiry)
+}
+
+// Token returns the current access token value, rotating it first if it is
+// about to expire.
+func (s *RotatingTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tokens.recovery != nil {
+		if token, ok := s.tokens.recovery.current(s.token.Value); ok && token.Value != s.token.Value {
+			s.set(token)
+			if s.onRotate != nil {
+				s.onRotate(token)
+			}
+		}
+	}
+	if s.expired() {
+		if err := s.rotate(ctx); err != nil {
+			return "", err
+		}
+	}
+	return s.token.Value, nil
+}
+
+// AccessToken returns the current access token without rotating it.
+func (s *RotatingTokenSource) AccessToken() as.AccessToken {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.token
+}
+
+// Invalidate marks the current token as expired so the next call to Token
+// rotates it, e.g. after a resource server rejected it.
+func (s *RotatingTokenSource) Invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expiry = s.now()
+}
+
+func (s *RotatingTokenSource) rotate(ctx context.Context) error {
+	token, err := s.tokens.Rotate(ctx, TokenRotateParams{
+		URL:         s.token.Manage,
+		AccessToken: s.token.Value,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rotate access token: %w", err)
+	}
+
+	s.set(token)
+	if s.onRotate != nil {
+		s.onRotate(token)
+	}
+	return nil
+}
+
+func setAuthorization(req *http.Request, accessToken string, source TokenSource) error {
+	if source != nil {
+		token, err := source.Token(req.Context())
+		if err != nil {
+			return fmt.Errorf("failed to get access token: %w", err)
+		}
+		accessToken = token
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("GNAP %s", accessToken))
+	return nil
+}
@@ -0,0 +1,125 @@
+package openpayments_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	openpayments "github.com/interledger/open-payments-go"
+	as "github.com/interledger/open-payments-go/generated/authserver"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRotationServer(t *testing.T, expiresIn int) (*httptest.Server, *atomic.Int32, *[]string) {
+	t.Helper()
+	var rotations atomic.Int32
+	var mu sync.Mutex
+	var authorizations []string
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/token/"):
+			n := rotations.Add(1)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"access_token": as.AccessToken{
+					Value:     fmt.Sprintf("token-%d", n),
+					Manage:    fmt.Sprintf("%s/token/%d", server.URL, n),
+					ExpiresIn: &expiresIn,
+				},
+			})
+		default:
+			mu.Lock()
+			authorizations = append(authorizations, r.Header.Get("Authorization"))
+			mu.Unlock()
+			_ = json.NewEncoder(w).Encode(openpayments.OutgoingPaymentGrantSpentAmounts{})
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &rotations, &authorizations
+}
+
+func TestRotatingTokenSource_RotatesBeforeExpiry(t *testing.T) {
+	server, rotations, authorizations := newRotationServer(t, 3600)
+
+	client, err := openpayments.NewAuthenticatedClient(walletAddress, pk, keyID, openpayments.WithHTTPClientAuthed(server.Client()))
+	assert.NoError(t, err)
+
+	expiresIn := 5
+	var rotated []as.AccessToken
+	source := openpayments.NewRotatingTokenSource(client.Token, as.AccessToken{
+		Value:     "token-0",
+		Manage:    server.URL + "/token/0",
+		ExpiresIn: &expiresIn,
+	}, openpayments.WithTokenRotateHook(func(token as.AccessToken) {
+		rotated = append(rotated, token)
+	}))
+
+	for i := 0; i < 2; i++ {
+		_, err = client.OutgoingPayment.GetGrantSpentAmounts(context.Background(), openpayments.OutgoingPaymentGrantGetParams{
+			BaseURL:     server.URL,
+			TokenSource: source,
+		})
+		assert.NoError(t, err)
+	}
+
+	assert.Equal(t, int32(1), rotations.Load())
+	assert.Equal(t, []string{"GNAP token-1", "GNAP token-1"}, *authorizations)
+	assert.Len(t, rotated, 1)
+	assert.Equal(t, "token-1", source.AccessToken().Value)
+
+	source.Invalidate()
+	token, err := source.Token(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "token-2", token)
+}
+
+func TestRotatingTokenSource_DedupesConcurrentRotation(t *testing.T) {
+	server, rotations, _ := newRotationServer(t, 3600)
+
+	client, err := openpayments.NewAuthenticatedClient(walletAddress, pk, keyID, openpayments.WithHTTPClientAuthed(server.Client()))
+	assert.NoError(t, err)
+
+	expiresIn := 0
+	source := openpayments.NewRotatingTokenSource(client.Token, as.AccessToken{
+		Value:     "token-0",
+		Manage:    server.URL + "/token/0",
+		ExpiresIn: &expiresIn,
+	})
+
+	var wg sync.WaitGroup
+	tokens := make([]string, 10)
+	for i := range tokens {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tokens[i], _ = source.Token(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), rotations.Load())
+	for _, token := range tokens {
+		assert.Equal(t, "token-1", token)
+	}
+}
+
+func TestStaticTokenSource(t *testing.T) {
+	server, _, authorizations := newRotationServer(t, 3600)
+
+	client, err := openpayments.NewAuthenticatedClient(walletAddress, pk, keyID, openpayments.WithHTTPClientAuthed(server.Client()))
+	assert.NoError(t, err)
+
+	_, err = client.OutgoingPayment.GetGrantSpentAmounts(context.Background(), openpayments.OutgoingPaymentGrantGetParams{
+		BaseURL:     server.URL,
+		TokenSource: openpayments.StaticTokenSource(accessToken),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"GNAP " + accessToken}, *authorizations)
+}